@@ -0,0 +1,131 @@
+package dlog
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/dimcz/dlog/ansi"
+	"github.com/dimcz/dlog/config"
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/utils"
+	"github.com/dimcz/dlog/watch"
+
+	"github.com/nsf/termbox-go"
+)
+
+const (
+	watchInterval  = 100 * time.Millisecond
+	commandsQueued = 16
+)
+
+// alert is a line which fired watch rule
+type alert struct {
+	v       *viewer
+	rule    *watch.Rule
+	line    []byte
+	offset  Offset // offset of the line in file of version
+	version int64
+}
+
+func loadRules() ([]*watch.Rule, error) {
+	path := config.GetValue().Watch
+	if path == "" {
+		path = filepath.Join(dlogDir, "watch.json")
+	}
+
+	return watch.Load(utils.ExpandHomePath(path))
+}
+
+// watch evaluates rules on lines written to memfile, i.e. arriving while following. Rules are skipped
+// while another pane shows the same log. Commands are run one by one, alerts coming while queue is full are not run
+func (v *viewer) watch(ctx context.Context, rules []*watch.Rule) {
+	commands := make(chan alert, commandsQueued)
+	defer close(commands)
+	go func() {
+		for a := range commands {
+			logging.LogOnErr(a.rule.Run(ctx, a.line))
+		}
+	}()
+
+	file := v.fetcher.reader
+	_, _, written := file.Written(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchInterval):
+		}
+
+		version := file.Version()
+		data, start, total := file.Written(written)
+		if file.Version() != version {
+			continue // history was inserted meanwhile, offset of data is not known
+		}
+		end := bytes.LastIndexByte(data, '\n')
+		if end == -1 {
+			continue // waiting for the line to be completed
+		}
+		written = total - int64(len(data)-end-1)
+		if atomic.LoadInt32(&v.watching) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		for pos := 0; pos <= end; {
+			line := data[pos : pos+bytes.IndexByte(data[pos:], '\n')]
+			for _, r := range rules {
+				if !r.Match(line, now) {
+					continue
+				}
+				a := alert{v: v, rule: r, line: append([]byte(nil), line...), offset: Offset(start) + Offset(pos), version: version}
+				if r.Actions&watch.ActionRun != 0 {
+					select {
+					case commands <- a:
+					default:
+						logging.Debug("commands queue is full, skipping", r.Command)
+					}
+				}
+				if r.Actions&^watch.ActionRun != 0 {
+					go termbox.Interrupt()
+					select {
+					case requestAlert <- a:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			pos += len(line) + 1
+		}
+	}
+}
+
+// alert is called from event loop, when rule is fired
+func (v *viewer) alert(a alert) {
+	if a.rule.Actions&watch.ActionPause != 0 && v.following {
+		// the line is not shown when file was cleared since, e.g. by switching container
+		if offset, ok := v.fetcher.resolveOffset(a.offset, a.version); ok {
+			v.paused = true
+			v.following = false
+			v.direction = DirectionUP
+			v.buffer.reset(Pos{POS_UNKNOWN, offset})
+			v.draw()
+		}
+	}
+	if a.rule.Actions&watch.ActionBell != 0 {
+		ringBell()
+	}
+	if a.rule.Actions&watch.ActionFlash != 0 && v.focus == v {
+		v.info.setMessage(ibMessage{
+			str:   "Alert " + a.rule.Name + ": " + string(ansi.NewAstring(a.line).Runes),
+			color: termbox.ColorRed | termbox.AttrReverse | termbox.AttrBold,
+		})
+	}
+}
+
+// ringBell writes BEL to the terminal termbox draws on
+func ringBell() {
+	logging.LogOnErr(utils.WriteTerminal([]byte{'\a'}))
+}
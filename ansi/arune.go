@@ -2,29 +2,43 @@ package ansi
 
 import (
 	"bytes"
+	"math"
 	"strconv"
 	"strings"
-
-	"github.com/dimcz/dlog/runes"
+	"unicode/utf8"
 )
 
+// RuneAttr keeps SGR attributes of a rune. Fg and Bg are 0 for default color,
+// palette index + 1 for 16 and 256 colors or 24-bit value with ColorRGB flag set.
+// Basic colors, set by 30-37 and 40-47, are marked with ColorBasic flag
 type RuneAttr struct {
-	Fg    uint8
-	Bg    uint8
-	Style uint8
+	Fg    uint32
+	Bg    uint32
+	Style Style
+	Link  uint16 // index in Links of Astring + 1, 0 if rune is not a part of hyperlink
 }
 
 type Astring struct {
 	Runes []rune
 	Attrs []RuneAttr
+	Links []string // targets of OSC 8 hyperlinks
 }
 
-type Style uint8
+type Style uint16
+
+//goland:noinspection GoUnusedConst
+const StyleNormal Style = 0
 
 //goland:noinspection GoUnusedConst
 const (
-	StyleNormal Style = iota
-	StyleBold
+	StyleBold Style = 1 << iota
+	StyleDim
+	StyleItalic
+	StyleUnderline
+	StyleBlink
+	StyleReverse
+	StyleHidden
+	StyleStrike
 )
 
 type Color uint8
@@ -39,97 +53,405 @@ const (
 	ColorMagenta
 	ColorCyan
 	ColorGray
+	ColorBright // added to the colors above gives their bright variant, e.g. ColorBright + ColorRed
 )
 
-func FgColor(color Color) uint8 {
-	return uint8(color) + 30
+// ColorRGB marks Fg and Bg values holding 24-bit color
+const ColorRGB uint32 = 1 << 24
+
+// ColorBasic marks one of 8 basic colors, which is shown bright with bold, as terminals do.
+// The same palette colors set by 38;5;n are kept as they are
+const ColorBasic uint32 = 1 << 25
+
+// FgColor returns value of basic color, like the one set by 30-37
+func FgColor(color Color) uint32 {
+	return ColorBasic | PaletteColor(uint8(color))
 }
 
+// BgColor returns value of basic color, like the one set by 40-47
+//
 //goland:noinspection GoUnusedExportedFunction
-func BgColor(color Color) uint8 {
-	return uint8(color) + 40
+func BgColor(color Color) uint32 {
+	return ColorBasic | PaletteColor(uint8(color))
+}
+
+// PaletteColor returns color value for index of 256 colors palette
+func PaletteColor(index uint8) uint32 {
+	return uint32(index) + 1
 }
 
-// NewAstring returns new Astring, struct containing bytes converted to runes and ansi attributes per rune
+func RGBColor(r, g, b uint8) uint32 {
+	return ColorRGB | uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+}
+
+// NewAstring returns new Astring, struct containing bytes converted to runes and ansi attributes per rune.
+// Control sequences other than styling are stripped, carriage return moves back to the line start,
+// so the following text overwrites the previous one the same way as progress bars are shown in terminal
 func NewAstring(src []byte) Astring {
-	var distance int
 	var attr RuneAttr
 	var r rune
 
 	rr := bytes.Runes(src)
 	max := len(rr)
 	astring := Astring{
-		make([]rune, max),
-		make([]RuneAttr, max),
+		Runes: make([]rune, max),
+		Attrs: make([]RuneAttr, max),
 	}
-	ri := 0
-mainLoop:
+	ri := 0        // position to write next rune to, it moves back on carriage return
+	end := 0       // length of written runes
+	lineStart := 0 // carriage return moves back here
 	for i := 0; i < len(rr); i++ {
 		r = rr[i]
-		if r == 27 && i != max-1 { // [27 91] is control sequence
-			if rr[i+1] == 91 {
-				// TODO: For now ignoring all escape sequences other then styling
-				distance = runes.IndexRune(rr[i+2:], 'm')
-				if distance > 7 {
-					continue // 000;000 is maximal distance, if m is further - something went wrong
-				}
-				if distance == -1 {
+		if r == 27 && i != max-1 {
+			switch rr[i+1] {
+			case '[': // [27 91] is control sequence
+				seqEnd := csiEnd(rr[i+2:])
+				if seqEnd == -1 {
 					i = i + 1
 					continue
 				}
-				attr.Fg, attr.Bg, attr.Style = 0, 0, 0
-				if distance != 0 {
-					data := string(rr[i+2 : i+2+distance])
-					formats := strings.Split(data, ";")
-					for _, format := range formats {
-						// TODO: Can be optimized by using bytes directly
-						f, err := strconv.Atoi(format)
-						if err == nil {
-							if f >= 30 && f <= 37 {
-								attr.Fg = uint8(f)
-							} else if f >= 40 && f <= 47 {
-								attr.Bg = uint8(f)
-							} else if f != 0 {
-								attr.Style = uint8(f)
-							}
-						} else {
-							// If could not parse as string - we are reading it wrong
-							// Ignoring control sequence itself, but not ditching characters
-							continue mainLoop
-						}
+				if rr[i+2+seqEnd] == 'm' {
+					next, ok := attr.applySGR(string(rr[i+2 : i+2+seqEnd]))
+					if !ok {
+						// If could not parse - we are reading it wrong
+						// Ignoring control sequence itself, but not ditching characters
+						continue
 					}
+					attr = next
 				}
-				i = i + 2 + distance
+				i = i + 2 + seqEnd // cursor movement, erasing and others are stripped
 				continue
-				// Control sequence
-			}
-			if rr[i+1] == 40 || rr[i+1] == 41 { // [27 {40,41}] is charset shift sequence, ignore
+			case ']', 'P', 'X', '^', '_': // operating system command and other strings up to terminator
+				data, n := controlString(rr[i+2:])
+				if rr[i+1] == ']' {
+					attr.Link = astring.link(data, attr.Link)
+				}
+				i = i + 1 + n
+				continue
+			case '(', ')': // [27 {40,41}] is charset shift sequence, ignore
 				i += 2 // all shift sequences are two bytes
 				continue
+			default:
+				if rr[i+1] >= 0x30 && rr[i+1] <= 0x7e { // two bytes sequences, like saving cursor
+					i++
+					continue
+				}
 			}
 		} else if r == 8 { // CTRL+H/Backspace
-			if i > 0 && len(rr) > i+1 {
+			if i > 0 && len(rr) > i+1 && ri > 0 {
 				prevChar := rr[i-1]
 				nextChar := rr[i+1]
 				i++ // Will move 1 char forward to skip next char, we are using it now
 				astring.Runes[ri-1] = nextChar
 				if prevChar == nextChar {
 					astring.Attrs[ri-1].Fg = FgColor(ColorRed)
-					astring.Attrs[ri-1].Style = uint8(StyleBold)
+					astring.Attrs[ri-1].Style = StyleBold
 				} else if prevChar == '_' {
 					astring.Attrs[ri-1].Fg = FgColor(ColorGreen)
-					astring.Attrs[ri-1].Style = uint8(StyleBold)
+					astring.Attrs[ri-1].Style = StyleBold
 				}
 				continue // No need to advance ri, used previous one
 
 			}
+		} else if r == '\r' {
+			ri = lineStart
+			continue
+		} else if r == '\n' {
+			ri = end
+			lineStart = end + 1
 		}
 		astring.Runes[ri] = r
 		astring.Attrs[ri] = attr
 		ri++
+		if ri > end {
+			end = ri
+		}
 	}
-	astring.Runes = astring.Runes[:ri]
-	astring.Attrs = astring.Attrs[:ri]
+	astring.Runes = astring.Runes[:end]
+	astring.Attrs = astring.Attrs[:end]
 
 	return astring
 }
+
+// IsPlain reports whether NewAstring keeps text of src as is, i.e. src is valid UTF-8
+// without escape sequences, backspaces and carriage returns
+func IsPlain(src []byte) bool {
+	ascii := true
+	for _, c := range src {
+		switch {
+		case c == 27 || c == 8 || c == '\r':
+			return false
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+
+	return ascii || utf8.Valid(src)
+}
+
+// controlString returns data of string started by ESC ] and alike, terminated by BEL or ESC \,
+// with number of runes including terminator. Unterminated string lasts up to the end
+func controlString(rr []rune) (string, int) {
+	for i, r := range rr {
+		if r == 7 {
+			return string(rr[:i]), i + 1
+		}
+		if r == 27 && i+1 < len(rr) && rr[i+1] == '\\' {
+			return string(rr[:i]), i + 2
+		}
+	}
+
+	return string(rr), len(rr)
+}
+
+// link handles OSC 8 hyperlink 8;params;URI, it returns link index for following runes,
+// which is 0 when link ends, or current one for other commands
+func (a *Astring) link(osc string, current uint16) uint16 {
+	if !strings.HasPrefix(osc, "8;") {
+		return current
+	}
+
+	uri := osc[2:]
+	if i := strings.IndexByte(uri, ';'); i != -1 {
+		uri = uri[i+1:]
+	}
+	if uri == "" {
+		return 0
+	}
+
+	if len(a.Links) == math.MaxUint16 {
+		return 0 // index of link would wrap, further links are shown as plain text
+	}
+	a.Links = append(a.Links, uri)
+
+	return uint16(len(a.Links))
+}
+
+// LinkAt returns target of OSC 8 hyperlink of i-th rune, if any
+func (a Astring) LinkAt(i int) (string, bool) {
+	if i < 0 || i >= len(a.Attrs) || a.Attrs[i].Link == 0 || int(a.Attrs[i].Link) > len(a.Links) {
+		return "", false
+	}
+
+	return a.Links[a.Attrs[i].Link-1], true
+}
+
+// csiEnd returns index of the final byte of control sequence, which starts after ESC [, or -1
+func csiEnd(rr []rune) int {
+	for i, r := range rr {
+		switch {
+		case r >= 0x20 && r <= 0x3f: // parameter and intermediate bytes
+		case r >= 0x40 && r <= 0x7e:
+			return i
+		default:
+			return -1
+		}
+	}
+
+	return -1
+}
+
+var sgrStyles = map[int]Style{
+	1: StyleBold,
+	2: StyleDim,
+	3: StyleItalic,
+	4: StyleUnderline,
+	5: StyleBlink,
+	6: StyleBlink,
+	7: StyleReverse,
+	8: StyleHidden,
+	9: StyleStrike,
+	// double underline
+	21: StyleUnderline,
+}
+
+var sgrResets = map[int]Style{
+	22: StyleBold | StyleDim,
+	23: StyleItalic,
+	24: StyleUnderline,
+	25: StyleBlink,
+	27: StyleReverse,
+	28: StyleHidden,
+	29: StyleStrike,
+}
+
+// applySGR returns attr changed by parameters of Select Graphic Rendition sequence, like 1;38;5;208.
+// Parameters are applied in order, empty one is the same as 0 which resets styling
+func (a RuneAttr) applySGR(params string) (RuneAttr, bool) {
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		if strings.Contains(codes[i], ":") {
+			if !a.applySubparams(strings.Split(codes[i], ":")) {
+				return a, false
+			}
+			continue
+		}
+
+		code, ok := sgrCode(codes[i])
+		if !ok {
+			return a, false
+		}
+
+		switch {
+		case code == 0:
+			a = RuneAttr{Link: a.Link} // hyperlink is not styling, it lasts until closed
+		case sgrStyles[code] != 0:
+			a.Style |= sgrStyles[code]
+		case sgrResets[code] != 0:
+			a.Style &^= sgrResets[code]
+		case code >= 30 && code <= 37:
+			a.Fg = FgColor(Color(code - 30))
+		case code >= 40 && code <= 47:
+			a.Bg = BgColor(Color(code - 40))
+		case code >= 90 && code <= 97:
+			a.Fg = PaletteColor(uint8(code - 90 + int(ColorBright)))
+		case code >= 100 && code <= 107:
+			a.Bg = PaletteColor(uint8(code - 100 + int(ColorBright)))
+		case code == 39:
+			a.Fg = 0
+		case code == 49:
+			a.Bg = 0
+		case code == 38 || code == 48:
+			color, n, ok := extendedColor(codes[i+1:])
+			if !ok {
+				return a, false
+			}
+			i += n
+			if code == 38 {
+				a.Fg = color
+			} else {
+				a.Bg = color
+			}
+		}
+	}
+
+	return a, true
+}
+
+// applySubparams handles colon separated forms, like 38:2::255:128:0 or 4:3
+func (a *RuneAttr) applySubparams(sub []string) bool {
+	code, ok := sgrCode(sub[0])
+	if !ok {
+		return false
+	}
+
+	switch code {
+	case 38, 48:
+		if len(sub) == 6 && sub[1] == "2" {
+			sub = append(sub[:2], sub[3:]...) // color space id is skipped
+		}
+		color, _, ok := extendedColor(sub[1:])
+		if !ok {
+			return false
+		}
+		if code == 38 {
+			a.Fg = color
+		} else {
+			a.Bg = color
+		}
+	case 4:
+		if style, _ := sgrCode(sub[1]); style == 0 {
+			a.Style &^= StyleUnderline
+		} else {
+			a.Style |= StyleUnderline
+		}
+	}
+
+	return true
+}
+
+// extendedColor parses 5;n and 2;r;g;b following 38 or 48 and returns number of used parameters
+func extendedColor(params []string) (uint32, int, bool) {
+	if len(params) == 0 {
+		return 0, 0, false
+	}
+
+	values := make([]uint8, 0, 4)
+	for _, p := range params {
+		v, ok := sgrCode(p)
+		if !ok || v > 255 {
+			return 0, 0, false
+		}
+		values = append(values, uint8(v))
+		if len(values) == 4 || values[0] == 5 && len(values) == 2 {
+			break
+		}
+	}
+
+	switch {
+	case values[0] == 5 && len(values) == 2:
+		return PaletteColor(values[1]), 2, true
+	case values[0] == 2 && len(values) == 4:
+		return RGBColor(values[1], values[2], values[3]), 4, true
+	}
+
+	return 0, 0, false
+}
+
+func sgrCode(s string) (int, bool) {
+	if s == "" {
+		return 0, true
+	}
+	code, err := strconv.Atoi(s)
+
+	return code, err == nil && code >= 0
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// Index256 returns index of color in 256 colors palette, 24-bit colors are approximated
+// by the closest one of 6x6x6 cube or grayscale ramp
+func Index256(color uint32) uint8 {
+	if color&ColorRGB == 0 {
+		return uint8(color&^ColorBasic - 1)
+	}
+
+	r, g, b := int(color>>16&0xff), int(color>>8&0xff), int(color&0xff)
+	closest := func(v int) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if abs(level-v) < abs(cubeLevels[best]-v) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := closest(r), closest(g), closest(b)
+	cubeDistance := sq(cubeLevels[ri]-r) + sq(cubeLevels[gi]-g) + sq(cubeLevels[bi]-b)
+
+	gray := ((r+g+b)/3 - 3) / 10
+	if gray < 0 {
+		gray = 0
+	}
+	if gray > 23 {
+		gray = 23
+	}
+	level := 8 + gray*10
+	grayDistance := sq(level-r) + sq(level-g) + sq(level-b)
+
+	if grayDistance < cubeDistance {
+		return uint8(232 + gray)
+	}
+
+	return uint8(16 + 36*ri + 6*gi + bi)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func sq(v int) int {
+	return v * v
+}
+
+// AppendString appends runes of s, all of them having the same attr
+func (a *Astring) AppendString(s string, attr RuneAttr) {
+	for _, r := range s {
+		a.Runes = append(a.Runes, r)
+		a.Attrs = append(a.Attrs, attr)
+	}
+}
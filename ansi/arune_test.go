@@ -0,0 +1,127 @@
+package ansi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplySGR(t *testing.T) {
+	bold := RuneAttr{Style: StyleBold, Fg: FgColor(ColorRed)}
+	linked := RuneAttr{Style: StyleBold, Fg: FgColor(ColorRed), Link: 1}
+	tests := []struct {
+		attr   RuneAttr
+		params string
+		want   RuneAttr
+		ok     bool
+	}{
+		{RuneAttr{}, "31", RuneAttr{Fg: FgColor(ColorRed)}, true},
+		{RuneAttr{}, "38;5;1", RuneAttr{Fg: PaletteColor(1)}, true},
+		{RuneAttr{}, "42", RuneAttr{Bg: BgColor(ColorGreen)}, true},
+		{RuneAttr{}, "91", RuneAttr{Fg: PaletteColor(9)}, true},
+		{RuneAttr{}, "107", RuneAttr{Bg: PaletteColor(15)}, true},
+		{RuneAttr{}, "38;5;208", RuneAttr{Fg: PaletteColor(208)}, true},
+		{RuneAttr{}, "48;5;0", RuneAttr{Bg: PaletteColor(0)}, true},
+		{RuneAttr{}, "38;2;255;128;0", RuneAttr{Fg: RGBColor(255, 128, 0)}, true},
+		{RuneAttr{}, "1;4;38;5;208;48;2;0;0;0", RuneAttr{Fg: PaletteColor(208), Bg: RGBColor(0, 0, 0), Style: StyleBold | StyleUnderline}, true},
+		{RuneAttr{}, "38:2::255:128:0", RuneAttr{Fg: RGBColor(255, 128, 0)}, true},
+		{RuneAttr{}, "38:2:255:128:0", RuneAttr{Fg: RGBColor(255, 128, 0)}, true},
+		{RuneAttr{}, "48:5:17", RuneAttr{Bg: PaletteColor(17)}, true},
+		{RuneAttr{}, "4:3", RuneAttr{Style: StyleUnderline}, true},
+		{RuneAttr{Style: StyleUnderline}, "4:0", RuneAttr{}, true},
+		{RuneAttr{}, "2;3;5;7;8;9", RuneAttr{Style: StyleDim | StyleItalic | StyleBlink | StyleReverse | StyleHidden | StyleStrike}, true},
+		{bold, "0", RuneAttr{}, true},
+		{bold, "", RuneAttr{}, true},
+		{linked, "0", RuneAttr{Link: 1}, true},
+		{bold, "22", RuneAttr{Fg: FgColor(ColorRed)}, true},
+		{bold, "39", RuneAttr{Style: StyleBold}, true},
+		{RuneAttr{Bg: BgColor(ColorGreen), Style: StyleItalic | StyleUnderline}, "49;23;24", RuneAttr{}, true},
+		{bold, "0;32", RuneAttr{Fg: FgColor(ColorGreen)}, true},
+		{RuneAttr{}, "38;5", RuneAttr{}, false},
+		{RuneAttr{}, "38;2;255;128", RuneAttr{}, false},
+		{RuneAttr{}, "38;5;256", RuneAttr{}, false},
+		{RuneAttr{}, "38;7;1", RuneAttr{}, false},
+		{RuneAttr{}, "3x", RuneAttr{}, false},
+	}
+
+	for i, tt := range tests {
+		got, ok := tt.attr.applySGR(tt.params)
+		if ok != tt.ok || ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("test %d, applySGR(%q):\ngot  %+v %v\nwant %+v %v", i, tt.params, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtendedColor(t *testing.T) {
+	tests := []struct {
+		params []string
+		color  uint32
+		n      int
+		ok     bool
+	}{
+		{[]string{"5", "208"}, PaletteColor(208), 2, true},
+		{[]string{"5", "208", "1"}, PaletteColor(208), 2, true},
+		{[]string{"2", "1", "2", "3"}, RGBColor(1, 2, 3), 4, true},
+		{[]string{"2", "1", "2", "3", "4"}, RGBColor(1, 2, 3), 4, true},
+		{[]string{"2", "", "", ""}, RGBColor(0, 0, 0), 4, true},
+		{[]string{}, 0, 0, false},
+		{[]string{"5"}, 0, 0, false},
+		{[]string{"2", "1", "2"}, 0, 0, false},
+		{[]string{"2", "1", "2", "300"}, 0, 0, false},
+		{[]string{"9", "1"}, 0, 0, false},
+	}
+
+	for i, tt := range tests {
+		color, n, ok := extendedColor(tt.params)
+		if color != tt.color || n != tt.n || ok != tt.ok {
+			t.Errorf("test %d, extendedColor(%q):\ngot  %x %d %v\nwant %x %d %v", i, tt.params, color, n, ok, tt.color, tt.n, tt.ok)
+		}
+	}
+}
+
+func TestNewAstring(t *testing.T) {
+	red := RuneAttr{Fg: FgColor(ColorRed)}
+	orange := RuneAttr{Fg: PaletteColor(208), Style: StyleBold}
+	tests := []struct {
+		src   string
+		runes string
+		attrs []RuneAttr
+	}{
+		{"ab", "ab", []RuneAttr{{}, {}}},
+		{"\x1b[31ma\x1b[0mb", "ab", []RuneAttr{red, {}}},
+		{"\x1b[1;38;5;208ma\x1b[mb", "ab", []RuneAttr{orange, {}}},
+		{"\x1b[38;2;255;128;0ma", "a", []RuneAttr{{Fg: RGBColor(255, 128, 0)}}},
+		{"\x1b[91ma\x1b[39mb", "ab", []RuneAttr{{Fg: PaletteColor(9)}, {}}},
+		{"\x1b[2Ka\x1b[1Gb", "ab", []RuneAttr{{}, {}}},
+		{"ab\rc", "cb", []RuneAttr{{}, {}}},
+	}
+
+	for i, tt := range tests {
+		a := NewAstring([]byte(tt.src))
+		if string(a.Runes) != tt.runes || !reflect.DeepEqual(a.Attrs, tt.attrs) {
+			t.Errorf("test %d, NewAstring(%q):\ngot  %q %+v\nwant %q %+v", i, tt.src, string(a.Runes), a.Attrs, tt.runes, tt.attrs)
+		}
+	}
+}
+
+func TestIndex256(t *testing.T) {
+	tests := []struct {
+		color uint32
+		want  uint8
+	}{
+		{PaletteColor(0), 0},
+		{PaletteColor(9), 9},
+		{FgColor(ColorRed), 1},
+		{PaletteColor(208), 208},
+		{RGBColor(0, 0, 0), 16},
+		{RGBColor(255, 255, 255), 231},
+		{RGBColor(255, 0, 0), 196},
+		{RGBColor(255, 135, 0), 208},
+		{RGBColor(128, 128, 128), 244},
+	}
+
+	for i, tt := range tests {
+		if got := Index256(tt.color); got != tt.want {
+			t.Errorf("test %d, Index256(%x):\ngot  %d\nwant %d", i, tt.color, got, tt.want)
+		}
+	}
+}
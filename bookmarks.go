@@ -0,0 +1,198 @@
+package dlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dimcz/dlog/timestamp"
+
+	"github.com/nsf/termbox-go"
+)
+
+// bookmark anchors line by its timestamp and hash of its text, unlike line number and offset
+// they stay the same when history is inserted in front of the line or logs are read again
+type bookmark struct {
+	Time time.Time `json:"time"`
+	Hash uint64    `json:"hash"`
+}
+
+// bookmarks of logs by their names, bookmarks of a log are keyed by letter
+type bookmarks map[string]map[string]bookmark
+
+func bookmarksPath() string {
+	return filepath.Join(dlogDir, "bookmarks.json")
+}
+
+func loadBookmarks() (bookmarks, error) {
+	marks := bookmarks{}
+
+	data, err := os.ReadFile(bookmarksPath())
+	if os.IsNotExist(err) {
+		return marks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return marks, json.Unmarshal(data, &marks)
+}
+
+// saveBookmark sets bookmark of log, the file is read again, so bookmarks of other panes are kept
+func saveBookmark(log string, name rune, b bookmark) error {
+	marks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	if marks[log] == nil {
+		marks[log] = map[string]bookmark{}
+	}
+	marks[log][string(name)] = b
+
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dlogDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(bookmarksPath(), data, 0600)
+}
+
+func isBookmarkName(ch rune) bool {
+	return ch < unicode.MaxASCII && (unicode.IsLetter(ch) || unicode.IsDigit(ch))
+}
+
+// firstLine returns the first line of multi-line entry, bookmark doesn't depend on grouping
+func firstLine(text []byte) []byte {
+	if i := bytes.IndexByte(text, '\n'); i != -1 {
+		return text[:i]
+	}
+
+	return text
+}
+
+func newBookmark(text []byte) bookmark {
+	line := firstLine(text)
+	t, _ := timestamp.ParseBytes(line)
+
+	return bookmark{Time: t, Hash: hashLine(line)}
+}
+
+func hashLine(line []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(line)
+
+	return h.Sum64()
+}
+
+// searchBookmark returns position of bookmarked line, lines logged since time of bookmark are searched first
+func (f *Fetcher) searchBookmark(ctx context.Context, b bookmark) Pos {
+	match := func(str []byte) []int {
+		if line := firstLine(str); hashLine(line) == b.Hash {
+			return []int{0, len(line)}
+		}
+		return nil
+	}
+
+	if !b.Time.IsZero() {
+		if offset, ok := f.searchTime(b.Time); ok {
+			if pos := f.Search(ctx, Pos{POS_UNKNOWN, offset}, match); pos != POS_NOT_FOUND {
+				return pos
+			}
+		}
+	}
+
+	return f.Search(ctx, Pos{0, 0}, match)
+}
+
+// startMark waits for name of bookmark typed after key, m sets bookmark and ' jumps to it
+func (v *viewer) startMark(key rune) {
+	if key == 'm' {
+		v.markKey = key
+		v.info.setMessage(ibMessage{str: "Bookmark current line as: a-z, A-Z, 0-9", color: termbox.ColorGreen})
+		return
+	}
+
+	marks, err := loadBookmarks()
+	if err != nil {
+		v.info.setMessage(ibMessage{str: "Err: bookmarks: " + err.Error(), color: termbox.ColorRed})
+		return
+	}
+	if len(marks[v.logName]) == 0 {
+		v.info.setMessage(ibMessage{str: "No bookmarks", color: termbox.ColorRed})
+		return
+	}
+
+	names := make([]string, 0, len(marks[v.logName]))
+	for name := range marks[v.logName] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	v.markKey = key
+	v.info.setMessage(ibMessage{str: "Jump to bookmark: " + strings.Join(names, " "), color: termbox.ColorGreen})
+}
+
+// processMarkKey handles name of bookmark, any other key cancels it
+func (v *viewer) processMarkKey(ev termbox.Event) {
+	key := v.markKey
+	v.markKey = 0
+	if !isBookmarkName(ev.Ch) {
+		return
+	}
+
+	switch key {
+	case 'm':
+		v.setBookmark(ev.Ch)
+	case '\'':
+		v.jumpToBookmark(ev.Ch)
+	}
+}
+
+func (v *viewer) setBookmark(name rune) {
+	l := v.currentLine()
+	if len(l.Text) == 0 {
+		v.info.setMessage(ibMessage{str: "Err: no line to bookmark", color: termbox.ColorRed})
+		return
+	}
+
+	if err := saveBookmark(v.logName, name, newBookmark(l.Text)); err != nil {
+		v.info.setMessage(ibMessage{str: "Err: bookmarks: " + err.Error(), color: termbox.ColorRed})
+		return
+	}
+	v.info.setMessage(ibMessage{str: fmt.Sprintf("Bookmark %c is set", name), color: termbox.ColorGreen})
+}
+
+func (v *viewer) jumpToBookmark(name rune) {
+	marks, err := loadBookmarks()
+	if err != nil {
+		v.info.setMessage(ibMessage{str: "Err: bookmarks: " + err.Error(), color: termbox.ColorRed})
+		return
+	}
+	b, ok := marks[v.logName][string(name)]
+	if !ok {
+		v.info.setMessage(ibMessage{str: fmt.Sprintf("Bookmark %c is not set", name), color: termbox.ColorRed})
+		return
+	}
+
+	pos := v.fetcher.searchBookmark(context.TODO(), b)
+	if pos == POS_NOT_FOUND {
+		v.info.setMessage(ibMessage{str: fmt.Sprintf("Line of bookmark %c is not found", name), color: termbox.ColorRed})
+		return
+	}
+
+	v.direction = DirectionUP
+	v.following = false
+	v.buffer.reset(pos)
+	v.draw()
+}
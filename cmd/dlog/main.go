@@ -13,6 +13,7 @@ import (
 const VERSION = "1.0.0"
 
 func main() {
+	config.Parse()
 	if config.GetValue().Version {
 		fmt.Println("Dlog Version: ", VERSION)
 		os.Exit(0)
@@ -20,7 +21,19 @@ func main() {
 
 	logging.Debug("<-- DLOG -->", VERSION)
 
-	d, err := dlog.NewWithDocker()
+	var d *dlog.Dlog
+	var err error
+	switch {
+	case config.GetValue().JSONLogs != "":
+		d, err = dlog.NewWithJSONFiles(config.GetValue().JSONLogs)
+	case len(config.GetValue().Files) > 0:
+		d, err = dlog.NewWithFiles(config.GetValue().Files)
+	case !utils.IsTerminal(os.Stdin):
+		// termbox reads keys from /dev/tty, so stdin is free to carry the logs
+		d = dlog.NewWithReader("stdin", os.Stdin)
+	default:
+		d, err = dlog.NewWithDocker()
+	}
 	utils.ExitOnErr(err)
 
 	defer d.Shutdown()
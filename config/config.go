@@ -1,22 +1,61 @@
 package config
 
-import "flag"
+import (
+	"flag"
+	"regexp"
+)
 
 type Config struct {
-	Version   bool
-	Tail      int
-	NoLoad    bool
-	TimeShift int64
+	Version    bool
+	Tail       int
+	NoLoad     bool
+	TimeShift  int64
+	JSONLogs   string
+	JSONFields string
+	Host       string
+	Context    string
+	TLSCACert  string
+	TLSCert    string
+	TLSKey     string
+	TLSVerify  bool
+	Errors     *regexp.Regexp
+	Watch      string
+	Files      []string
 }
 
 var values Config
 
+const defaultErrors = `(?i)\b(error|fatal|panic|exception)\b`
+
 func init() {
 	flag.BoolVar(&(values.Version), "version", false, "Print version information")
 	flag.IntVar(&(values.Tail), "tail", 1_000, "Number of lines to show from the end of the logs")
 	flag.BoolVar(&(values.NoLoad), "noload", true, "Disable loading previous logs")
 	flag.Int64Var(&(values.TimeShift), "shift", 24*60*60, "time chunk to download logs")
+	flag.StringVar(&(values.JSONLogs), "jsonlogs", "",
+		"Read json-file logs without docker daemon: containers directory, container directory or *-json.log file")
+	flag.StringVar(&(values.JSONFields), "jsonfields", "level|lvl|severity,msg|message",
+		"JSON fields shown first in compact form of JSON lines, alternatives separated by |, empty keeps raw JSON")
+	flag.StringVar(&(values.Host), "host", "", "Daemon socket to connect to, e.g. unix:///run/user/1000/podman/podman.sock")
+	flag.StringVar(&(values.Context), "context", "", "Name of the docker context to use (see `docker context ls`)")
+	flag.StringVar(&(values.TLSCACert), "tlscacert", "", "Trust certs signed only by this CA")
+	flag.StringVar(&(values.TLSCert), "tlscert", "", "Path to TLS certificate file")
+	flag.StringVar(&(values.TLSKey), "tlskey", "", "Path to TLS key file")
+	flag.BoolVar(&(values.TLSVerify), "tlsverify", false, "Use TLS and verify the remote")
+	values.Errors = regexp.MustCompile(defaultErrors)
+	flag.Func("errors", "Pattern of lines counted in error rate of status bar (default \""+defaultErrors+"\")",
+		func(s string) (err error) {
+			values.Errors, err = regexp.Compile(s)
+			return err
+		})
+	flag.StringVar(&(values.Watch), "watch", "", "JSON file with alert rules evaluated while following (default ~/.dlog/watch.json)")
+}
+
+// Parse parses command line, it is called by main rather than init, so flags of tests are not taken as own ones
+func Parse() {
 	flag.Parse()
+
+	values.Files = flag.Args()
 }
 
 func GetValue() Config {
@@ -0,0 +1,125 @@
+package dlog
+
+import (
+	"fmt"
+
+	"github.com/dimcz/dlog/logging"
+
+	"github.com/nsf/termbox-go"
+)
+
+// containersView is an overlay listing containers or files of the source, selected one is opened in the pane
+type containersView struct {
+	v        *viewer
+	names    []string
+	current  int
+	selected int
+	top      int
+}
+
+func (v *viewer) showContainers() {
+	if v.containers == nil {
+		return
+	}
+
+	names, current := v.containers()
+	v.focus = &containersView{v: v, names: names, current: current, selected: current}
+	v.draw()
+}
+
+func (cv *containersView) draw() {
+	cv.v.clear()
+
+	cv.v.printRow(0, fmt.Sprintf("%d containers. j/k: select  Enter: open  q: close", len(cv.names)),
+		termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+
+	rows := cv.v.rows - 1
+	if cv.selected < cv.top {
+		cv.top = cv.selected
+	}
+	if cv.selected >= cv.top+rows {
+		cv.top = cv.selected - rows + 1
+	}
+
+	for y := 0; y < rows && cv.top+y < len(cv.names); y++ {
+		i := cv.top + y
+		fg := termbox.ColorDefault
+		if i == cv.selected {
+			fg |= termbox.AttrReverse
+		}
+		mark := "  "
+		if i == cv.current {
+			mark = "* "
+		}
+		cv.v.printRow(y+1, mark+cv.names[i], fg, termbox.ColorDefault)
+	}
+
+	logging.LogOnErr(termbox.Flush())
+}
+
+func (cv *containersView) move(direction int) {
+	cv.selected += direction
+	if cv.selected >= len(cv.names) {
+		cv.selected = len(cv.names) - 1
+	}
+	if cv.selected < 0 {
+		cv.selected = 0
+	}
+	cv.draw()
+}
+
+// open closes the overlay and opens selected container
+func (cv *containersView) open() action {
+	cv.v.resetFocus()
+	if cv.selected != cv.current {
+		cv.v.selectContainer(cv.selected)
+	}
+
+	return NO_ACTION
+}
+
+func (cv *containersView) processKey(ev termbox.Event) action {
+	if ev.Ch != 0 {
+		switch ev.Ch {
+		case 'q':
+			return ACTION_RESET_FOCUS
+		case 'j':
+			cv.move(+1)
+		case 'k':
+			cv.move(-1)
+		case 'g':
+			cv.move(-len(cv.names))
+		case 'G':
+			cv.move(len(cv.names))
+		}
+		return NO_ACTION
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return ACTION_RESET_FOCUS
+	case termbox.KeyEnter:
+		return cv.open()
+	case termbox.KeyArrowDown:
+		cv.move(+1)
+	case termbox.KeyArrowUp:
+		cv.move(-1)
+	}
+	return NO_ACTION
+}
+
+func (cv *containersView) processMouse(ev termbox.Event, _, y int) action {
+	switch ev.Key {
+	case termbox.MouseWheelUp:
+		cv.move(-1)
+	case termbox.MouseWheelDown:
+		cv.move(+1)
+	case termbox.MouseRelease:
+		if i := cv.top + y - 1; y > 0 && i < len(cv.names) {
+			cv.selected = i
+			return cv.open()
+		}
+	}
+
+	return NO_ACTION
+}
@@ -0,0 +1,180 @@
+package dlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dimcz/dlog/ansi"
+	"github.com/dimcz/dlog/pattern"
+	"github.com/dimcz/dlog/timestamp"
+)
+
+type dedupMode uint8
+
+const (
+	dedupOff dedupMode = iota
+	dedupExact
+	dedupSimilar
+)
+
+var dedupModeNames = map[dedupMode]string{
+	dedupOff:     "off",
+	dedupExact:   "identical lines",
+	dedupSimilar: "similar lines",
+}
+
+// deduper collapses runs of consecutive duplicate lines into the first line of the run.
+// Lines are compared without timestamp, in dedupSimilar mode numbers, UUIDs etc. are masked as well
+type deduper struct {
+	mode    dedupMode
+	held    Line
+	heldKey string
+	holding bool
+}
+
+func (d *deduper) key(l Line) string {
+	_, n := timestamp.Parse(l.Str.Runes)
+	payload := string(l.Str.Runes[n:])
+	if d.mode == dedupSimilar {
+		return pattern.Mask(payload)
+	}
+
+	return payload
+}
+
+func lineTime(l Line) string {
+	_, n := timestamp.Parse(l.Str.Runes)
+	if n == 0 {
+		return ""
+	}
+
+	return string(l.Str.Runes[:n-1])
+}
+
+// push returns line ready to be sent, false if l is collapsed into held line.
+// Backward reading receives lines in reverse order, so the run is collapsed into its earliest line
+func (d *deduper) push(l Line, backward bool) (Line, bool) {
+	if d.mode == dedupOff {
+		return l, true
+	}
+
+	key := d.key(l)
+	if d.holding && key == d.heldKey {
+		if !backward {
+			d.held.Repeated = repeated(d.held) + 1
+			d.held.LastTime = lineTime(l)
+			return Line{}, false
+		}
+		lastTime := d.held.LastTime
+		if lastTime == "" {
+			lastTime = lineTime(d.held)
+		}
+		l.Repeated = repeated(d.held) + 1
+		l.LastTime = lastTime
+		d.held = l
+		return Line{}, false
+	}
+
+	prev, ok := d.flush()
+	d.held, d.heldKey, d.holding = l, key, true
+
+	return prev, ok
+}
+
+// flush returns held line, if any
+func (d *deduper) flush() (Line, bool) {
+	if !d.holding {
+		return Line{}, false
+	}
+	d.holding = false
+
+	return d.held, true
+}
+
+func repeated(l Line) int {
+	if l.Repeated == 0 {
+		return 1
+	}
+
+	return l.Repeated
+}
+
+var repeatAttr = ansi.RuneAttr{Fg: ansi.FgColor(ansi.ColorYellow)}
+
+// appendRepeats adds counter and time range of collapsed duplicates to displayed line
+func appendRepeats(str ansi.Astring, l Line) ansi.Astring {
+	out := ansi.Astring{
+		Runes: append([]rune(nil), str.Runes...),
+		Attrs: append([]ansi.RuneAttr(nil), str.Attrs...),
+		Links: str.Links,
+	}
+
+	suffix := fmt.Sprintf(" ×%d", l.Repeated)
+	first, _ := timestamp.Parse(l.Str.Runes)
+	if last, err := time.Parse(time.RFC3339Nano, l.LastTime); err == nil && !first.IsZero() {
+		suffix += fmt.Sprintf(" [%s..%s]", first.Format("15:04:05"), last.Format("15:04:05"))
+	}
+	out.AppendString(suffix, repeatAttr)
+
+	return out
+}
+
+// dedupLookaround limits entries read past the start of reading to find the whole run of duplicates
+const dedupLookaround = 1000
+
+// visibleEntry reads entry at offset and returns offset of the next one, which is the same at the end of file.
+// ok is false when entry is filtered out or there is none
+func (f *Fetcher) visibleEntry(offset Offset) (l Line, next Offset, ok bool) {
+	f.seek(offset)
+	b, _, err := f.readline()
+	if len(b) == 0 && err != nil {
+		return Line{}, offset, false
+	}
+	l = f.filteredLine(PosLine{b, Pos{POS_UNKNOWN, offset}})
+
+	return l, f.lineReaderOffset, l.Pos.Line != POS_FILTERED_OUT
+}
+
+// runStart returns start of the run of duplicates entry at offset belongs to and number of entries
+// before offset, so forward reading starting inside the run collapses it into its first line, as backward one does
+func (f *Fetcher) runStart(offset Offset, dd *deduper) (Offset, LineNo) {
+	l, _, ok := f.visibleEntry(offset)
+	if !ok {
+		return offset, 0
+	}
+	key := dd.key(l)
+
+	start, moved := offset, LineNo(0)
+	for i, cur := 1, offset; i <= dedupLookaround && cur > 0; i++ {
+		cur = f.entryStart(f.lineStartBefore(cur))
+		prev, _, ok := f.visibleEntry(cur)
+		if !ok {
+			continue
+		}
+		if dd.key(prev) != key {
+			break
+		}
+		start, moved = cur, LineNo(i)
+	}
+
+	return start, moved
+}
+
+// runAfter returns duplicates following entry at offset, they continue the run backward reading starts with
+func (f *Fetcher) runAfter(offset Offset, dd *deduper) (key string, n int, lastTime string) {
+	_, next, _ := f.visibleEntry(offset)
+	for i := 0; i < dedupLookaround && next > offset; i++ {
+		var l Line
+		var ok bool
+		offset = next
+		if l, next, ok = f.visibleEntry(offset); !ok {
+			continue
+		}
+		if n > 0 && dd.key(l) != key {
+			break
+		}
+		key, n, lastTime = dd.key(l), n+1, lineTime(l)
+	}
+
+	return key, n, lastTime
+}
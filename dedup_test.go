@@ -0,0 +1,65 @@
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimcz/dlog/memfile"
+)
+
+// TestDedupRuns checks that run of duplicates is one line with the same count, wherever reading starts
+func TestDedupRuns(t *testing.T) {
+	data := "2022-07-14T10:00:00Z start\n" +
+		"2022-07-14T10:00:01Z retry\n" +
+		"2022-07-14T10:00:02Z retry\n" +
+		"2022-07-14T10:00:03Z retry\n" +
+		"2022-07-14T10:00:04Z retry\n" +
+		"2022-07-14T10:00:05Z done\n"
+	at := func(line string) Offset { return Offset(strings.Index(data, line)) }
+	retry := at("2022-07-14T10:00:01Z retry")
+	want := []string{"start", "retry ×4 2022-07-14T10:00:04Z", "done"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := NewFetcher(ctx, memfile.New([]byte(data)))
+	f.dedup = dedupExact
+	show := func(l Line) string {
+		s := string(l.Text[len("2022-07-14T10:00:00Z "):])
+		if l.Repeated > 1 {
+			s += fmt.Sprintf(" ×%d %s", l.Repeated, l.LastTime)
+		}
+		return s
+	}
+
+	tests := []struct {
+		from     Offset
+		backward bool
+		want     []string
+	}{
+		{0, false, want},
+		{retry, false, want[1:]},
+		{at("2022-07-14T10:00:03Z"), false, want[1:]},
+		{at("2022-07-14T10:00:05Z"), true, []string{"done", want[1], "start"}},
+		{at("2022-07-14T10:00:04Z"), true, []string{want[1], "start"}},
+		{at("2022-07-14T10:00:02Z"), true, []string{want[1], "start"}},
+	}
+	for i, tt := range tests {
+		lines := f.Get(ctx, Pos{POS_UNKNOWN, tt.from})
+		if tt.backward {
+			lines = f.GetBack(ctx, Pos{POS_UNKNOWN, tt.from})
+		}
+		var got []string
+		for l := range lines {
+			got = append(got, show(l))
+			if l.Repeated > 1 && l.Offset != retry {
+				t.Errorf("test %d, run is collapsed into line at %d, want %d", i, l.Offset, retry)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("test %d, from %d, backward %v:\ngot  %q\nwant %q", i, tt.from, tt.backward, got, tt.want)
+		}
+	}
+}
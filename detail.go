@@ -0,0 +1,214 @@
+package dlog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dimcz/dlog/fields"
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/timestamp"
+	"github.com/dimcz/dlog/utils"
+
+	"github.com/nsf/termbox-go"
+)
+
+const detailKeyWidth = 24 // field keys are padded up to it
+
+// detailItem is a part of the line, which can be selected and copied
+type detailItem struct {
+	name  string
+	value string
+}
+
+type detailRow struct {
+	text string
+	item int // row is highlighted when item is selected, -1 for labels
+}
+
+// detailView is an overlay showing current line in full with its timestamp, container, stream
+// and fields. The line or selected field is copied to clipboard with OSC 52
+type detailView struct {
+	v        *viewer
+	title    string
+	items    []detailItem
+	rows     []detailRow
+	selected int
+	top      int
+	message  ibMessage
+}
+
+func newDetailView(v *viewer, l Line) *detailView {
+	dv := &detailView{v: v, title: "Line " + l.Pos.String()}
+
+	at, stream := "-", "-"
+	if t, n := timestamp.ParseBytes(l.Text); n != 0 {
+		at = t.Format(time.RFC3339Nano)
+	}
+	if v.stream != nil {
+		stream = v.stream(int64(l.Offset))
+	}
+	dv.addLabel("Time       " + at)
+	dv.addLabel("Container  " + v.logName)
+	dv.addLabel("Stream     " + stream)
+
+	dv.addLabel("")
+	dv.addLabel("Text")
+	dv.addItem("line", string(l.Text), "  ", "  ")
+
+	if f, ok := fields.ParseBytes(firstLine(l.Text)); ok {
+		keyWidth := 0
+		for _, field := range f {
+			if len(field.Key) > keyWidth && len(field.Key) <= detailKeyWidth {
+				keyWidth = len(field.Key)
+			}
+		}
+
+		dv.addLabel("")
+		dv.addLabel("Fields")
+		for _, field := range f {
+			dv.addItem(field.Key, field.Value, fmt.Sprintf("  %-*s  ", keyWidth, field.Key), strings.Repeat(" ", keyWidth+4))
+		}
+	}
+
+	return dv
+}
+
+func (dv *detailView) addLabel(str string) {
+	dv.rows = append(dv.rows, detailRow{text: str, item: -1})
+}
+
+// addItem adds rows of value, prefix is put in front of its first row and indent in front of others
+func (dv *detailView) addItem(name, value, prefix, indent string) {
+	dv.items = append(dv.items, detailItem{name: name, value: value})
+	for i, line := range strings.Split(value, "\n") {
+		if i == 0 {
+			dv.addRows(prefix+line, indent)
+		} else {
+			dv.addRows(indent+line, indent)
+		}
+	}
+}
+
+// addRows wraps str into rows of the last item, continuation rows are indented
+func (dv *detailView) addRows(str, indent string) {
+	item := len(dv.items) - 1
+	rs := []rune(strings.ReplaceAll(str, "\t", "    "))
+	for {
+		end, col := len(rs), 0
+		for _, gr := range graphemes(rs) {
+			if col+gr.width > dv.v.width && gr.start > len(indent) {
+				end = gr.start
+				break
+			}
+			col += gr.width
+		}
+
+		dv.rows = append(dv.rows, detailRow{text: string(rs[:end]), item: item})
+		if end == len(rs) {
+			return
+		}
+		rs = append([]rune(indent), rs[end:]...)
+	}
+}
+
+func (dv *detailView) draw() {
+	dv.v.clear()
+
+	if dv.message.str != "" {
+		dv.v.printRow(0, dv.message.str, dv.message.color, termbox.ColorDefault)
+	} else {
+		dv.v.printRow(0, dv.title+". j/k: select  Enter,y: copy  f/b: scroll  q: close",
+			termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+	}
+
+	for y := 0; y < dv.v.rows-1 && dv.top+y < len(dv.rows); y++ {
+		row := dv.rows[dv.top+y]
+		fg := termbox.ColorDefault
+		if row.item == dv.selected {
+			fg |= termbox.AttrReverse
+		}
+		dv.v.printRow(y+1, row.text, fg, termbox.ColorDefault)
+	}
+
+	logging.LogOnErr(termbox.Flush())
+}
+
+// move selects next or previous item, scrolling to its first row
+func (dv *detailView) move(direction int) {
+	dv.selected += direction
+	if dv.selected >= len(dv.items) {
+		dv.selected = len(dv.items) - 1
+	}
+	if dv.selected < 0 {
+		dv.selected = 0
+	}
+
+	for i, row := range dv.rows {
+		if row.item == dv.selected {
+			if i < dv.top || i >= dv.top+dv.v.rows-1 {
+				dv.top = i
+			}
+			break
+		}
+	}
+	dv.draw()
+}
+
+func (dv *detailView) scroll(direction int) {
+	dv.top += direction
+	if dv.top > len(dv.rows)-(dv.v.rows-1) {
+		dv.top = len(dv.rows) - (dv.v.rows - 1)
+	}
+	if dv.top < 0 {
+		dv.top = 0
+	}
+	dv.draw()
+}
+
+func (dv *detailView) copy() {
+	item := dv.items[dv.selected]
+	if err := utils.CopyToClipboard(item.value); err != nil {
+		dv.message = ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed}
+	} else {
+		dv.message = ibMessage{str: fmt.Sprintf("Copied %s to clipboard", item.name), color: termbox.ColorGreen}
+	}
+	dv.draw()
+}
+
+func (dv *detailView) processKey(ev termbox.Event) action {
+	dv.message = ibMessage{}
+	if ev.Ch != 0 {
+		switch ev.Ch {
+		case 'q':
+			return ACTION_RESET_FOCUS
+		case 'j':
+			dv.move(+1)
+		case 'k':
+			dv.move(-1)
+		case 'f':
+			dv.scroll(dv.v.rows - 1)
+		case 'b':
+			dv.scroll(-(dv.v.rows - 1))
+		case 'y':
+			dv.copy()
+		}
+		return NO_ACTION
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return ACTION_RESET_FOCUS
+	case termbox.KeyEnter:
+		dv.copy()
+	case termbox.KeyArrowDown:
+		dv.move(+1)
+	case termbox.KeyArrowUp:
+		dv.move(-1)
+	case termbox.KeyPgdn, termbox.KeySpace:
+		dv.scroll(dv.v.rows - 1)
+	case termbox.KeyPgup:
+		dv.scroll(-(dv.v.rows - 1))
+	}
+	return NO_ACTION
+}
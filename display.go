@@ -0,0 +1,113 @@
+package dlog
+
+import (
+	"github.com/dimcz/dlog/filters"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// grapheme is a user-perceived character, like letter with combining marks or emoji sequence,
+// it takes runes from start up to end and width columns on the screen
+type grapheme struct {
+	start, end int
+	width      int
+}
+
+// graphemes splits runes into grapheme clusters. Control characters other than line feed
+// take a column as they are drawn as space, clusters of zero width, like lone marks, take none
+func graphemes(rs []rune) []grapheme {
+	res := make([]grapheme, 0, len(rs))
+	g := uniseg.NewGraphemes(string(rs))
+	start := 0
+	for g.Next() {
+		cluster := g.Runes()
+		gr := grapheme{start: start, end: start + len(cluster), width: runewidth.StringWidth(string(cluster))}
+		if cluster[0] < ' ' && cluster[0] != '\n' {
+			gr.width = 1
+		}
+		res = append(res, gr)
+		start = gr.end
+	}
+
+	return res
+}
+
+// columnIndex returns index of the first rune shown at column col or further,
+// wide character crossing the column is skipped as it can't be drawn in half
+func columnIndex(rs []rune, col int) int {
+	x := 0
+	for _, gr := range graphemes(rs) {
+		if x >= col {
+			return gr.start
+		}
+		x += gr.width
+	}
+
+	return len(rs)
+}
+
+// clusterRune returns rune grapheme is drawn with, as termbox cell keeps a single rune. Letter with
+// combining marks, like decomposed (NFD) accented one, is composed by NFC into precomposed rune.
+// Clusters without precomposed form, like flags and ZWJ emoji sequences, are drawn by their first rune
+func clusterRune(cluster []rune) rune {
+	if len(cluster) == 1 {
+		return cluster[0]
+	}
+
+	return []rune(norm.NFC.String(string(cluster)))[0]
+}
+
+// sourceIndex maps src through rendered, which holds index in src of each rendered rune or -1 for added one.
+// Nil rendered means runes are not rendered and src is kept
+func sourceIndex(src, rendered []int) []int {
+	if rendered == nil {
+		return src
+	}
+	res := make([]int, len(rendered))
+	for i, r := range rendered {
+		res[i] = -1
+		if r >= 0 {
+			res[i] = src[r]
+		}
+	}
+
+	return res
+}
+
+// appendedIndex returns src of runes, which start with prev ones and have added runes after them,
+// like counter of duplicates or number of folded lines
+func appendedIndex(src []int, prev, runes []rune) []int {
+	n := 0
+	for n < len(prev) && n < len(runes) && prev[n] == runes[n] {
+		n++
+	}
+	res := append(src[:n:n], make([]int, len(runes)-n)...)
+	for i := n; i < len(res); i++ {
+		res[i] = -1
+	}
+
+	return res
+}
+
+// highlightedRunes reports which of shown runes are matched by search. Search is done in runes of line,
+// as lines are found by it, and matches are mapped to shown runes by src. Runes added for display,
+// like "=" of compact JSON or counter of duplicates, are not highlighted
+func highlightedRunes(indexAll filters.IndexAllFunc, runes, shown []rune, src []int) []bool {
+	hl := make([]bool, len(shown))
+	if indexAll == nil {
+		return hl
+	}
+	matched := make([]bool, len(runes))
+	for _, rng := range filters.IndexAll(indexAll, runes) {
+		for i := rng[0]; i < rng[1]; i++ {
+			matched[i] = true
+		}
+	}
+	for i, s := range src {
+		hl[i] = s >= 0 && matched[s]
+	}
+
+	return hl
+}
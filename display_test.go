@@ -0,0 +1,26 @@
+package dlog
+
+import (
+	"testing"
+)
+
+func TestClusterRune(t *testing.T) {
+	tests := []struct {
+		cluster string
+		want    rune
+	}{
+		{"a", 'a'},
+		{"e\u0301", 'é'},
+		{"A\u030a", 'Å'},
+		{"\u1100\u1161", '가'},
+		{"q\u0301", 'q'},
+		{"🇺🇦", '🇺'},
+		{"👩\u200d💻", '👩'},
+	}
+
+	for i, tt := range tests {
+		if got := clusterRune([]rune(tt.cluster)); got != tt.want {
+			t.Errorf("test %d, clusterRune(%q):\ngot  %q\nwant %q", i, tt.cluster, got, tt.want)
+		}
+	}
+}
@@ -6,62 +6,91 @@ import (
 	"sync"
 
 	"github.com/dimcz/dlog/docker"
+	"github.com/dimcz/dlog/logfile"
 	"github.com/dimcz/dlog/memfile"
+	"github.com/dimcz/dlog/stream"
+
+	"github.com/nsf/termbox-go"
 )
 
-type Dlog struct {
-	wg      *sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
-	file    *memfile.File
-	fetcher *Fetcher
-	docker  *docker.Docker
-	v       *viewer
+// Source fills memfile with log lines
+type Source interface {
+	Follow() int64
+	Append(start int64, callBack func())
+	Name() string
+	LogName() string // current container or file, state kept between runs, like bookmarks, is keyed by it
 }
 
-func (d *Dlog) GetFile() *memfile.File {
-	return d.file
+// switcher is implemented by sources, which are able to iterate over several logs
+type switcher interface {
+	NextContainer()
+	PrevContainer()
 }
 
-func (d *Dlog) Display() {
-	start := d.docker.Follow()
+// selector is implemented by sources, which list their logs to select one of them
+type selector interface {
+	Containers() ([]string, int)
+	SelectContainer(i int)
+}
 
-	d.fetcher = NewFetcher(d.ctx, d.file)
-	_, _ = d.file.Seek(0, io.SeekStart)
+// streamer is implemented by sources, which know stream of container, stdout or stderr, line was written to
+type streamer interface {
+	Stream(offset int64) string
+}
+
+// stopper is implemented by sources, which read logs in background until stopped
+type stopper interface {
+	Stop()
+}
 
-	d.v = NewViewer(
-		WithCtx(d.ctx),
-		WithFetcher(d.fetcher),
-		WithWrap(true),
-		WithKeyArrowRight(d.rightDirection),
-		WithKeyArrowLeft(d.leftDirection))
+// splitSource returns source reading the same logs as s into file, for a new pane
+func splitSource(ctx context.Context, s Source, file *memfile.File) (Source, bool) {
+	switch s := s.(type) {
+	case *docker.Docker:
+		return s.Split(ctx, file), true
+	case *docker.JSONFile:
+		return s.Split(ctx, file), true
+	case *logfile.LogFile:
+		return s.Split(ctx, file), true
+	}
 
-	d.v.termGui(d.docker.Name(), func() {
-		d.docker.Append(start, d.v.refill)
-	})
+	return nil, false
 }
 
-func (d *Dlog) rightDirection() {
-	d.v.initScreen()
-	d.docker.NextContainer()
-	d.reload()
+type Dlog struct {
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	file   *memfile.File
+	source Source
+
+	panes      []*pane
+	focused    int
+	split      split
+	syncScroll bool // panes follow timestamp of the focused one
 }
 
-func (d *Dlog) leftDirection() {
-	d.v.initScreen()
-	d.docker.PrevContainer()
-	d.reload()
+func (d *Dlog) GetFile() *memfile.File {
+	return d.file
 }
 
-func (d *Dlog) reload() {
-	start := d.docker.Follow()
+func (d *Dlog) Display() {
+	if err := termbox.Init(); err != nil {
+		panic(err)
+	}
+	defer termbox.Close()
 
-	d.v.setTerminalName(d.docker.Name())
+	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
+	termbox.SetOutputMode(termbox.Output256)
 
-	d.v.navigateEnd()
-	d.v.navigateEnd()
+	d.openPane(d.source, d.file)
+	defer func() {
+		for _, p := range d.panes {
+			p.close()
+		}
+	}()
 
-	d.docker.Append(start, d.v.refill)
+	d.loop()
 }
 
 func (d *Dlog) Shutdown() {
@@ -87,10 +116,52 @@ func NewWithDocker() (*Dlog, error) {
 
 	var err error
 
-	d.docker, err = docker.Client(d.ctx, memFile)
+	d.source, err = docker.Client(d.ctx, memFile)
 	if err != nil {
 		return nil, err
 	}
 
 	return d, nil
 }
+
+// NewWithJSONFiles returns Dlog reading json-file driver logs from disk, without docker daemon
+func NewWithJSONFiles(path string) (*Dlog, error) {
+	memFile := memfile.New([]byte{})
+
+	d := New(memFile)
+
+	var err error
+
+	d.source, err = docker.JSONFiles(d.ctx, memFile, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// NewWithFiles returns Dlog following files on disk, arrows switch between them
+func NewWithFiles(paths []string) (*Dlog, error) {
+	memFile := memfile.New([]byte{})
+
+	d := New(memFile)
+
+	var err error
+
+	d.source, err = logfile.Open(d.ctx, memFile, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// NewWithReader returns Dlog displaying everything read from r, e.g. piped stdin
+func NewWithReader(name string, r io.Reader) *Dlog {
+	memFile := memfile.New([]byte{})
+
+	d := New(memFile)
+	d.source = stream.New(memFile, name, r)
+
+	return d
+}
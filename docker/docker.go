@@ -14,8 +14,6 @@ import (
 	"github.com/dimcz/dlog/logging"
 	"github.com/dimcz/dlog/memfile"
 
-	"github.com/docker/docker/pkg/stdcopy"
-
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 )
@@ -30,6 +28,8 @@ type Docker struct {
 	containers []Container
 	current    int
 	cli        *client.Client
+	endpoint   Endpoint
+	streams    *streams
 
 	wg            *sync.WaitGroup
 	parentContext context.Context
@@ -57,7 +57,7 @@ func (d *Docker) followFrom(t int64) {
 		logging.LogOnErr(fd.Close())
 	}(fd)
 
-	if _, err := stdcopy.StdCopy(d.file, d.file, fd); err != nil {
+	if err := copyStreams(d.file, d.streams, fd); err != nil {
 		return
 	}
 }
@@ -67,7 +67,7 @@ func (d *Docker) Follow() int64 {
 
 	h := strconv.Itoa(config.GetValue().Tail)
 
-	d.file.Clear()
+	d.streams.clear(d.file)
 
 	logging.Debug(fmt.Sprintf("request %s first records", h))
 	start, end, err := d.retrieveAndParseLogs(types.ContainerLogsOptions{
@@ -82,6 +82,7 @@ func (d *Docker) Follow() int64 {
 	}
 
 	logging.Debug("execute following process")
+	d.file.Live()
 	d.wg.Add(1)
 	go d.followFrom(end)
 
@@ -128,11 +129,25 @@ func (d *Docker) appendSince(t int64, callBack func()) {
 }
 
 func Client(ctx context.Context, file *memfile.File) (*Docker, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	endpoint, err := ResolveEndpoint(config.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := endpoint.clientOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	if endpoint.String() == "" {
+		endpoint.Host = cli.DaemonHost()
+	}
+
 	if _, err := cli.Ping(context.Background()); err != nil {
 		return nil, err
 	}
@@ -146,7 +161,9 @@ func Client(ctx context.Context, file *memfile.File) (*Docker, error) {
 		parentContext: ctx,
 		file:          file,
 		cli:           cli,
+		endpoint:      endpoint,
 		containers:    containers,
+		streams:       newStreams(),
 		wg:            new(sync.WaitGroup),
 	}, nil
 }
@@ -167,16 +184,61 @@ func retrieveContainers(cli *client.Client) (containers []Container, err error)
 }
 
 func (d *Docker) Name() string {
-	return fmt.Sprintf("(%d/%d) %s (ID:%s)",
+	return fmt.Sprintf("(%d/%d) %s (ID:%s) @ %s",
 		d.current+1,
 		len(d.containers),
 		strings.Replace(d.containers[d.current].Name, "/", "", 1),
-		d.containers[d.current].ID[:12])
+		d.containers[d.current].ID[:12],
+		d.endpoint)
 }
 
-func (d *Docker) NextContainer() {
+// Split returns source reading the same daemon into file, starting from the current container
+func (d *Docker) Split(ctx context.Context, file *memfile.File) *Docker {
+	return &Docker{
+		parentContext: ctx,
+		file:          file,
+		cli:           d.cli,
+		endpoint:      d.endpoint,
+		containers:    d.containers,
+		current:       d.current,
+		streams:       newStreams(),
+		wg:            new(sync.WaitGroup),
+	}
+}
+
+// Stop cancels reading of logs and waits for it to finish
+func (d *Docker) Stop() {
 	d.cancel()
 	d.wg.Wait()
+}
+
+func (d *Docker) LogName() string {
+	return strings.Replace(d.containers[d.current].Name, "/", "", 1)
+}
+
+// Stream returns stream of container line at offset was written to
+func (d *Docker) Stream(offset int64) string {
+	return d.streams.stream(offset)
+}
+
+// Containers returns names of containers and index of the current one
+func (d *Docker) Containers() ([]string, int) {
+	names := make([]string, len(d.containers))
+	for i, c := range d.containers {
+		names[i] = strings.Replace(c.Name, "/", "", 1)
+	}
+
+	return names, d.current
+}
+
+func (d *Docker) SelectContainer(i int) {
+	d.Stop()
+
+	d.current = i
+}
+
+func (d *Docker) NextContainer() {
+	d.Stop()
 
 	c := d.current + 1
 	if c >= len(d.containers) {
@@ -186,8 +248,7 @@ func (d *Docker) NextContainer() {
 }
 
 func (d *Docker) PrevContainer() {
-	d.cancel()
-	d.wg.Wait()
+	d.Stop()
 
 	c := d.current - 1
 	if c < 0 {
@@ -207,9 +268,9 @@ func (d *Docker) retrieveLogs(options types.ContainerLogsOptions) (*memfile.File
 	}(fd)
 
 	mf := memfile.New([]byte{})
+	s := newStreams()
 
-	_, err = stdcopy.StdCopy(mf, mf, fd)
-	if err != nil {
+	if err := copyStreams(mf, s, fd); err != nil {
 		return nil, err
 	}
 
@@ -217,7 +278,7 @@ func (d *Docker) retrieveLogs(options types.ContainerLogsOptions) (*memfile.File
 		return nil, fmt.Errorf("retrieve empty logs")
 	}
 
-	if _, err := d.file.Insert(mf.Bytes()); err != nil {
+	if err := d.streams.insert(d.file, mf, s); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/dimcz/dlog/config"
+	"github.com/dimcz/dlog/utils"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+const defaultContext = "default"
+
+// Endpoint describes docker daemon to connect to, resolved from flags, docker contexts or environment
+type Endpoint struct {
+	Host          string
+	Context       string
+	CACert        string
+	Cert          string
+	Key           string
+	TLS           bool
+	SkipTLSVerify bool
+}
+
+type contextMeta struct {
+	Name      string
+	Endpoints map[string]struct {
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// ResolveEndpoint applies the same precedence as docker CLI: --host, --context, DOCKER_HOST,
+// DOCKER_CONTEXT and currentContext from docker config. Empty Host means defaults from environment
+func ResolveEndpoint(cfg config.Config) (Endpoint, error) {
+	e := Endpoint{
+		Host:   cfg.Host,
+		CACert: cfg.TLSCACert,
+		Cert:   cfg.TLSCert,
+		Key:    cfg.TLSKey,
+	}
+
+	if e.Host == "" {
+		name := cfg.Context
+		if name == "" && os.Getenv("DOCKER_HOST") == "" {
+			name = os.Getenv("DOCKER_CONTEXT")
+			if name == "" {
+				name = currentContext()
+			}
+		}
+
+		if name != "" && name != defaultContext {
+			if err := e.loadContext(name); err != nil {
+				return Endpoint{}, err
+			}
+		}
+	}
+
+	e.TLS = e.TLS || cfg.TLSVerify || e.CACert != "" || e.Cert != "" || e.Key != ""
+	if cfg.TLSVerify {
+		e.SkipTLSVerify = false
+	}
+
+	return e, nil
+}
+
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(utils.GetHomeDir(), ".docker")
+}
+
+func currentContext() string {
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+
+	var cfg struct{ CurrentContext string }
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+
+	return cfg.CurrentContext
+}
+
+// loadContext reads context metadata stored by `docker context create` under ~/.docker/contexts
+func (e *Endpoint) loadContext(name string) error {
+	id := sha256.Sum256([]byte(name))
+	dir := hex.EncodeToString(id[:])
+
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "contexts", "meta", dir, "meta.json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("context %q not found", name)
+	}
+	if err != nil {
+		return fmt.Errorf("context %q: %w", name, err)
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return fmt.Errorf("context %q: %w", name, err)
+	}
+
+	ep, ok := meta.Endpoints["docker"]
+	if !ok {
+		return fmt.Errorf("context %q has no docker endpoint", name)
+	}
+
+	e.Context = name
+	e.Host = ep.Host
+	e.SkipTLSVerify = ep.SkipTLSVerify
+
+	tlsDir := filepath.Join(dockerConfigDir(), "contexts", "tls", dir, "docker")
+	for path, file := range map[*string]string{&e.CACert: "ca.pem", &e.Cert: "cert.pem", &e.Key: "key.pem"} {
+		if *path != "" {
+			continue // flags take precedence
+		}
+		if _, err := os.Stat(filepath.Join(tlsDir, file)); err == nil {
+			*path = filepath.Join(tlsDir, file)
+			e.TLS = true
+		}
+	}
+
+	return nil
+}
+
+func (e Endpoint) clientOpts() ([]client.Opt, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+
+	if e.TLS {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             e.CACert,
+			CertFile:           e.Cert,
+			KeyFile:            e.Key,
+			InsecureSkipVerify: e.SkipTLSVerify,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsc},
+			CheckRedirect: client.CheckRedirect,
+		}))
+
+		// transport is replaced, so host has to be applied once again
+		if e.Host == "" {
+			e.Host = os.Getenv("DOCKER_HOST")
+		}
+		if e.Host == "" {
+			e.Host = client.DefaultDockerHost
+		}
+	}
+
+	if e.Host != "" {
+		opts = append(opts, client.WithHost(e.Host))
+	}
+
+	return opts, nil
+}
+
+// String returns short description of endpoint for status bar
+func (e Endpoint) String() string {
+	if e.Context != "" {
+		return e.Context
+	}
+
+	return e.Host
+}
@@ -0,0 +1,323 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimcz/dlog/logfile"
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/memfile"
+)
+
+// timestampFormat is used by docker daemon for timestamps in logs API output
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+const jsonLogSuffix = "-json.log"
+
+type jsonLogLine struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+type jsonLog struct {
+	Container
+	path string
+}
+
+// JSONFile reads logs written by json-file logging driver directly from disk,
+// so logs can be viewed without docker daemon, e.g. from post-mortem disk image
+type JSONFile struct {
+	file    *memfile.File
+	logs    []jsonLog
+	current int
+	streams *streams
+
+	wg            *sync.WaitGroup
+	parentContext context.Context
+	ctx           context.Context
+	cancel        func()
+}
+
+// JSONFiles discovers json-file logs in path, which is either a single *-json.log file,
+// a container directory or a directory of containers like /var/lib/docker/containers
+func JSONFiles(ctx context.Context, file *memfile.File, path string) (*JSONFile, error) {
+	logs, err := discoverJSONLogs(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no json-file logs found in %s", path)
+	}
+
+	return &JSONFile{
+		parentContext: ctx,
+		file:          file,
+		logs:          logs,
+		streams:       newStreams(),
+		wg:            new(sync.WaitGroup),
+	}, nil
+}
+
+func discoverJSONLogs(path string) ([]jsonLog, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		id := strings.TrimSuffix(filepath.Base(path), jsonLogSuffix)
+		return []jsonLog{newJSONLog(filepath.Dir(path), id, path)}, nil
+	}
+
+	id := filepath.Base(path)
+	if logPath := filepath.Join(path, id+jsonLogSuffix); isFile(logPath) {
+		return []jsonLog{newJSONLog(path, id, logPath)}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []jsonLog
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(path, e.Name())
+		if logPath := filepath.Join(dir, e.Name()+jsonLogSuffix); isFile(logPath) {
+			logs = append(logs, newJSONLog(dir, e.Name(), logPath))
+		}
+	}
+
+	return logs, nil
+}
+
+func newJSONLog(dir, id, path string) jsonLog {
+	name := id
+
+	// container name is kept by daemon in config.v2.json next to the log
+	if data, err := os.ReadFile(filepath.Join(dir, "config.v2.json")); err == nil {
+		var cfg struct{ Name string }
+		if err := json.Unmarshal(data, &cfg); err == nil && cfg.Name != "" {
+			name = cfg.Name
+		}
+	}
+
+	return jsonLog{Container{id, name}, path}
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+
+	return err == nil && fi.Mode().IsRegular()
+}
+
+func (j *JSONFile) Follow() int64 {
+	j.ctx, j.cancel = context.WithCancel(j.parentContext)
+
+	j.streams.clear(j.file)
+
+	logging.Debug("execute reading process", j.logs[j.current].path)
+	j.wg.Add(1)
+	go func(path string) {
+		defer j.wg.Done()
+		logging.LogOnErr(j.readLog(path, j.file, j.streams))
+	}(j.logs[j.current].path)
+
+	return -1
+}
+
+// Append inserts rotated .1, .2, ... files, optionally gzipped, in front of current log
+func (j *JSONFile) Append(_ int64, callBack func()) {
+	logging.Debug("execute append process")
+	j.wg.Add(1)
+	go j.appendRotated(j.logs[j.current].path, callBack)
+}
+
+func (j *JSONFile) appendRotated(path string, callBack func()) {
+	defer j.wg.Done()
+	defer logging.Timeit("append rotated logs")()
+
+	for i := 1; ; i++ {
+		select {
+		case <-j.ctx.Done():
+			return
+		default:
+		}
+
+		rotated := fmt.Sprintf("%s.%d", path, i)
+		if !isFile(rotated) {
+			rotated += ".gz"
+			if !isFile(rotated) {
+				return
+			}
+		}
+
+		mf := memfile.New([]byte{})
+		s := newStreams()
+		if err := j.readLog(rotated, mf, s); err != nil {
+			logging.Debug("failed to read rotated log:", err)
+			return
+		}
+
+		if err := j.streams.insert(j.file, mf, s); err != nil {
+			logging.Debug(err)
+			return
+		}
+
+		callBack()
+	}
+}
+
+func (j *JSONFile) readLog(path string, w io.Writer, s *streams) error {
+	defer logging.Timeit("read json log", path)()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func(f *os.File) {
+		logging.LogOnErr(f.Close())
+	}(f)
+
+	var r io.Reader = f
+
+	dr, err := logfile.Decompressor(f)
+	if err != nil {
+		return err
+	}
+	if dr != nil {
+		defer func(dr io.ReadCloser) {
+			logging.LogOnErr(dr.Close())
+		}(dr)
+		r = dr
+	}
+
+	return decodeJSONLog(j.ctx, r, w, s)
+}
+
+// decodeJSONLog converts json-file log entries into lines prefixed with timestamp,
+// same as the ones produced by logs API. Docker splits long lines into several partial
+// entries without trailing newline, those are joined back under the first timestamp.
+// Streams of entries written to w are remembered in s
+func decodeJSONLog(ctx context.Context, r io.Reader, w io.Writer, s *streams) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	bw := bufio.NewWriter(w)
+	partial := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return bw.Flush()
+		default:
+		}
+
+		var l jsonLogLine
+		if err := dec.Decode(&l); err != nil {
+			if err == io.EOF {
+				return bw.Flush()
+			}
+			logging.LogOnErr(bw.Flush())
+			return err
+		}
+
+		n := 0
+		if !partial {
+			n, _ = bw.WriteString(l.Time.UTC().Format(timestampFormat))
+			_ = bw.WriteByte(' ')
+			n++
+		}
+		written, _ := bw.WriteString(l.Log)
+		s.write(n+written, l.Stream == Stderr)
+
+		partial = !strings.HasSuffix(l.Log, "\n")
+	}
+}
+
+func (j *JSONFile) Name() string {
+	c := j.logs[j.current].Container
+	id := c.ID
+	if len(id) > 12 {
+		id = id[:12]
+	}
+
+	return fmt.Sprintf("(%d/%d) %s (ID:%s)",
+		j.current+1,
+		len(j.logs),
+		strings.Replace(c.Name, "/", "", 1),
+		id)
+}
+
+// Split returns source reading the same logs into file, starting from the current one
+func (j *JSONFile) Split(ctx context.Context, file *memfile.File) *JSONFile {
+	return &JSONFile{
+		parentContext: ctx,
+		file:          file,
+		logs:          j.logs,
+		current:       j.current,
+		streams:       newStreams(),
+		wg:            new(sync.WaitGroup),
+	}
+}
+
+// Stop cancels reading of logs and waits for it to finish
+func (j *JSONFile) Stop() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+func (j *JSONFile) LogName() string {
+	return strings.Replace(j.logs[j.current].Name, "/", "", 1)
+}
+
+// Stream returns stream of container line at offset was written to
+func (j *JSONFile) Stream(offset int64) string {
+	return j.streams.stream(offset)
+}
+
+// Containers returns names of containers and index of the current one
+func (j *JSONFile) Containers() ([]string, int) {
+	names := make([]string, len(j.logs))
+	for i, l := range j.logs {
+		names[i] = strings.Replace(l.Name, "/", "", 1)
+	}
+
+	return names, j.current
+}
+
+func (j *JSONFile) SelectContainer(i int) {
+	j.Stop()
+
+	j.current = i
+}
+
+func (j *JSONFile) NextContainer() {
+	j.Stop()
+
+	c := j.current + 1
+	if c >= len(j.logs) {
+		c = 0
+	}
+	j.current = c
+}
+
+func (j *JSONFile) PrevContainer() {
+	j.Stop()
+
+	c := j.current - 1
+	if c < 0 {
+		c = len(j.logs) - 1
+	}
+	j.current = c
+}
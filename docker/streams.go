@@ -0,0 +1,117 @@
+package docker
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/dimcz/dlog/memfile"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// streams remembers ranges of memfile container wrote to stderr, both streams are merged
+// into one memfile, so stream of line is looked up by its offset. Ranges are kept relative
+// to the first byte appended since clear, data inserted in front has negative positions,
+// so inserts do not move remembered ranges
+type streams struct {
+	lock     sync.RWMutex
+	stderr   []span // ordered, not overlapping
+	written  int64  // bytes appended since clear
+	inserted int64  // bytes inserted in front since clear
+}
+
+type span struct {
+	start, end int64
+}
+
+func newStreams() *streams {
+	return &streams{}
+}
+
+// write remembers n bytes appended to memfile from stdout or stderr
+func (s *streams) write(n int, stderr bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if stderr && n > 0 {
+		end := s.written + int64(n)
+		if last := len(s.stderr) - 1; last >= 0 && s.stderr[last].end == s.written {
+			s.stderr[last].end = end
+		} else {
+			s.stderr = append(s.stderr, span{s.written, end})
+		}
+	}
+	s.written += int64(n)
+}
+
+// insert inserts data of front memfile with its streams in front of file. Both are done under the lock,
+// so stream is not looked up between them, when offsets are moved already and ranges are not yet
+func (s *streams) insert(file, front *memfile.File, fs *streams) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := file.Insert(front.Bytes()); err != nil {
+		return err
+	}
+	shift := -s.inserted - fs.written
+	stderr := make([]span, 0, len(fs.stderr)+len(s.stderr))
+	for _, sp := range fs.stderr {
+		stderr = append(stderr, span{sp.start + shift, sp.end + shift})
+	}
+	s.stderr = append(stderr, s.stderr...)
+	s.inserted += fs.written
+
+	return nil
+}
+
+// clear clears file and forgets its streams under the lock, as insert does
+func (s *streams) clear(file *memfile.File) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	file.Clear()
+	s.stderr, s.written, s.inserted = nil, 0, 0
+}
+
+// stream returns Stderr for data at offset of memfile written to stderr, Stdout otherwise
+func (s *streams) stream(offset int64) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	pos := offset - s.inserted
+	i := sort.Search(len(s.stderr), func(i int) bool { return s.stderr[i].end > pos })
+	if i < len(s.stderr) && s.stderr[i].start <= pos {
+		return Stderr
+	}
+
+	return Stdout
+}
+
+// streamWriter writes one of container streams, remembering ranges of written data
+type streamWriter struct {
+	io.Writer
+	streams *streams
+	stderr  bool
+}
+
+func (w streamWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.streams.write(n, w.stderr)
+
+	return n, err
+}
+
+// copyStreams copies multiplexed stdout and stderr of container into w
+func copyStreams(w io.Writer, s *streams, r io.Reader) error {
+	_, err := stdcopy.StdCopy(streamWriter{w, s, false}, streamWriter{w, s, true}, r)
+
+	return err
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimcz/dlog/memfile"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// streamsOf returns stream of each line of file
+func streamsOf(file *memfile.File, s *streams) []string {
+	var got []string
+	offset := 0
+	for _, line := range strings.SplitAfter(string(file.Bytes()), "\n") {
+		if line != "" {
+			got = append(got, s.stream(int64(offset)))
+		}
+		offset += len(line)
+	}
+
+	return got
+}
+
+func TestStreams(t *testing.T) {
+	type frame struct {
+		stderr bool
+		data   string
+	}
+	tests := []struct {
+		history []frame // inserted in front
+		follow  []frame // appended
+		want    []string
+	}{
+		{
+			follow: []frame{{false, "a\n"}, {true, "b\n"}, {false, "c\n"}},
+			want:   []string{Stdout, Stderr, Stdout},
+		},
+		{
+			// the same text on both streams
+			follow: []frame{{true, "same\n"}, {false, "same\n"}, {true, "same\n"}},
+			want:   []string{Stderr, Stdout, Stderr},
+		},
+		{
+			// long line split into several frames
+			follow: []frame{{true, "long "}, {true, "line\n"}, {false, "out\n"}},
+			want:   []string{Stderr, Stdout},
+		},
+		{
+			history: []frame{{true, "old err\n"}, {false, "old out\n"}},
+			follow:  []frame{{false, "out\n"}, {true, "err\n"}},
+			want:    []string{Stderr, Stdout, Stdout, Stderr},
+		},
+	}
+
+	for i, tt := range tests {
+		file := memfile.New(nil)
+		s := newStreams()
+
+		history := new(bytes.Buffer)
+		for _, f := range tt.history {
+			w := stdcopy.NewStdWriter(history, stdcopy.Stdout)
+			if f.stderr {
+				w = stdcopy.NewStdWriter(history, stdcopy.Stderr)
+			}
+			_, _ = w.Write([]byte(f.data))
+		}
+
+		for _, f := range tt.follow {
+			buf := new(bytes.Buffer)
+			w := stdcopy.NewStdWriter(buf, stdcopy.Stdout)
+			if f.stderr {
+				w = stdcopy.NewStdWriter(buf, stdcopy.Stderr)
+			}
+			_, _ = w.Write([]byte(f.data))
+			if err := copyStreams(file, s, buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if history.Len() != 0 {
+			mf, front := memfile.New(nil), newStreams()
+			if err := copyStreams(mf, front, history); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.insert(file, mf, front); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := streamsOf(file, s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("test %d, streams:\ngot  %v\nwant %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeJSONLog(t *testing.T) {
+	log := `{"log":"out\n","stream":"stdout","time":"2022-07-14T10:00:00Z"}
+{"log":"part ","stream":"stderr","time":"2022-07-14T10:00:01Z"}
+{"log":"of err\n","stream":"stderr","time":"2022-07-14T10:00:02Z"}
+{"log":"out\n","stream":"stdout","time":"2022-07-14T10:00:03Z"}
+`
+	file := memfile.New(nil)
+	s := newStreams()
+	if err := decodeJSONLog(context.Background(), strings.NewReader(log), file, s); err != nil {
+		t.Fatal(err)
+	}
+
+	wantText := "2022-07-14T10:00:00.000000000Z out\n" +
+		"2022-07-14T10:00:01.000000000Z part of err\n" +
+		"2022-07-14T10:00:03.000000000Z out\n"
+	if got := string(file.Bytes()); got != wantText {
+		t.Errorf("decodeJSONLog text:\ngot  %q\nwant %q", got, wantText)
+	}
+	want := []string{Stdout, Stderr, Stdout}
+	if got := streamsOf(file, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeJSONLog streams:\ngot  %v\nwant %v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package dlog
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -11,8 +12,11 @@ import (
 
 	"github.com/dimcz/dlog/ansi"
 	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/level"
 	"github.com/dimcz/dlog/logging"
 	"github.com/dimcz/dlog/memfile"
+	"github.com/dimcz/dlog/multiline"
+	"github.com/dimcz/dlog/timestamp"
 )
 
 type Fetcher struct {
@@ -23,9 +27,14 @@ type Fetcher struct {
 	lineReader       *bufio.Reader
 	lineReaderOffset Offset
 	lineReaderPos    int
+	pending          *rawLine // line read ahead to find out if it continues current entry
+	grouping         bool     // multi-line entries, like stack traces, are read as one Line
+	dedup            dedupMode
 	filters          []*filters.Filter
+	filtersVersion   int64 // changed by every change of filters, even when their number stays the same
 	highlightedLines []LineNo
 	filtersEnabled   bool
+	cache            *lineCache // decoded lines, nil disables caching
 }
 
 //goland:noinspection GoSnakeCaseUsage
@@ -60,9 +69,27 @@ type PosLine struct {
 }
 
 type Line struct {
-	Str ansi.Astring
+	Str  ansi.Astring
+	Text []byte // runes of Str encoded as UTF-8, filters and searches run on it
 	Pos
 	Highlighted bool
+	Repeated    int    // number of collapsed duplicate lines, 0 if line is not collapsed
+	LastTime    string // timestamp of the last collapsed duplicate
+	level       *lineLevel
+}
+
+// Level returns log level of the line, it is detected once for cached lines
+func (l Line) Level() level.Level {
+	if l.level == nil {
+		return level.DetectBytes(l.Text)
+	}
+
+	return l.level.get(l.Text)
+}
+
+type rawLine struct {
+	b   []byte
+	err error
 }
 
 type offsetArr []Offset
@@ -73,9 +100,9 @@ func (a offsetArr) Less(i, j int) bool { return a[i] < a[j] }
 
 // Line == -1 if Line is excluded
 func (f *Fetcher) filteredLine(l PosLine) Line {
-	str := ansi.NewAstring(l.b)
+	str, text, lvl := f.cache.parse(l)
 	if len(f.filters) == 0 && len(f.highlightedLines) == 0 {
-		return Line{str, l.Pos, false}
+		return Line{Str: str, Text: text, Pos: l.Pos, level: lvl}
 	}
 	var filterResult filters.FilterResult
 	for _, highlighted := range f.highlightedLines {
@@ -88,16 +115,16 @@ func (f *Fetcher) filteredLine(l PosLine) Line {
 
 	for _, filter := range f.filters {
 		if f.filtersEnabled || filter.Action == filters.FilterHighlight {
-			filterResult = filter.TakeAction(str.Runes, filterResult)
+			filterResult = filter.TakeAction(text, filterResult)
 		}
 	}
 	switch filterResult {
 	case filters.FilterExcluded:
 		return Line{Pos: Pos{Line: POS_FILTERED_OUT, Offset: l.Pos.Offset}}
 	case filters.FilterHighlighted:
-		return Line{str, l.Pos, true}
+		return Line{Str: str, Text: text, Pos: l.Pos, Highlighted: true, level: lvl}
 	default:
-		return Line{str, l.Pos, false}
+		return Line{Str: str, Text: text, Pos: l.Pos, level: lvl}
 	}
 
 }
@@ -108,6 +135,8 @@ func NewFetcher(ctx context.Context, reader *memfile.File) *Fetcher {
 		lineMap:        map[Offset]LineNo{0: 0},
 		lineReader:     bufio.NewReaderSize(reader, ChunkSize),
 		filtersEnabled: true,
+		grouping:       true,
+		cache:          newLineCache(lineCacheLimit),
 	}
 
 	go f.gcMap(ctx)
@@ -141,7 +170,7 @@ func (f *Fetcher) seek(offset Offset) {
 	if offset < 0 {
 		panic("Seeking out of bounds")
 	}
-	if f.lineReaderOffset == offset && f.lineReader != nil {
+	if f.lineReaderOffset == offset && f.lineReader != nil && (f.pending == nil || f.pending.err == nil) {
 		return // We are already there
 	}
 	_, err := f.reader.Seek(int64(offset), io.SeekStart)
@@ -149,24 +178,70 @@ func (f *Fetcher) seek(offset Offset) {
 
 	f.lineReader = bufio.NewReaderSize(f.reader, ChunkSize)
 	f.lineReaderOffset = offset
+	f.pending = nil
 }
 
 // reads and returns one Line, position and error, which can only be io.EOF, otherwise panics
+// When grouping is enabled, continuation lines are joined to the Line they belong to
 func (f *Fetcher) readline() ([]byte, Offset, error) {
-	str, err := f.lineReader.ReadBytes('\n')
-	startingOffset := f.lineReaderOffset
-	if len(str) > 0 {
-		if err == nil {
-			f.lineReaderOffset += Offset(len(str))
+	str, startingOffset, err := f.readRawLine()
+	if !f.grouping || err != nil {
+		return str, startingOffset, err
+	}
+
+	prev := str
+	for {
+		next := f.peekRawLine()
+		if next.err != nil || !multiline.Continues(prev, next.b) {
+			return str, startingOffset, nil
 		}
+		_, _, _ = f.readRawLine()
+		str = append(append(str, '\n'), timestamp.Strip(next.b)...)
+		prev = next.b
 	}
+}
+
+// readRawLine reads one line of the file, the same way as readline does without grouping
+func (f *Fetcher) readRawLine() ([]byte, Offset, error) {
+	l := f.peekRawLine()
+	f.pending = nil
+	startingOffset := f.lineReaderOffset
+	if l.err == nil {
+		f.lineReaderOffset += Offset(len(l.b) + 1)
+	}
+
+	return l.b, startingOffset, l.err
+}
+
+func (f *Fetcher) peekRawLine() rawLine {
+	if f.pending != nil {
+		return *f.pending
+	}
+
+	str, err := f.lineReader.ReadBytes('\n')
 	if err == nil {
-		return str[:len(str)-1], startingOffset, err // TODO: Handle \r for windows logs?
-	} else if err == io.EOF {
-		return str, startingOffset, err
-	} else {
+		str = str[:len(str)-1] // TODO: Handle \r for windows logs?
+	} else if err != io.EOF {
 		panic(err)
 	}
+	f.pending = &rawLine{str, err}
+
+	return *f.pending
+}
+
+// switchGrouping enables or disables reading of multi-line entries as one Line.
+// Line numbers are counted in entries, so cached ones are dropped
+func (f *Fetcher) switchGrouping() {
+	f.lock.Lock()
+	f.grouping = !f.grouping
+	f.lineReader = nil
+	f.pending = nil
+	f.highlightedLines = f.highlightedLines[:0]
+	f.lock.Unlock()
+
+	f.mLock.Lock()
+	f.lineMap = map[Offset]LineNo{0: 0}
+	f.mLock.Unlock()
 }
 
 // Returns 2 channels: for consuming posLines and returning of built Line struct
@@ -178,6 +253,7 @@ func (f *Fetcher) lineBuilder(ctx context.Context) (chan<- PosLine, <-chan Line)
 	buffer := make([]Line, bufSize)
 	var ok bool
 	var l PosLine
+	dd := deduper{mode: f.dedup}
 	go func() {
 		bLen := 0
 		wg := sync.WaitGroup{}
@@ -187,8 +263,12 @@ func (f *Fetcher) lineBuilder(ctx context.Context) (chan<- PosLine, <-chan Line)
 				if buffer[i].Pos.Line == POS_FILTERED_OUT {
 					continue // filtered out
 				}
+				line, ok := dd.push(buffer[i], false)
+				if !ok {
+					continue // collapsed into previous one
+				}
 				select {
-				case lines <- buffer[i]:
+				case lines <- line:
 				case <-ctx.Done():
 					break
 				}
@@ -197,6 +277,14 @@ func (f *Fetcher) lineBuilder(ctx context.Context) (chan<- PosLine, <-chan Line)
 			wg = sync.WaitGroup{}
 		}
 		defer close(lines)
+		defer func() {
+			if line, ok := dd.flush(); ok {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+				}
+			}
+		}()
 		defer flush()
 		for {
 			select {
@@ -224,6 +312,12 @@ func (f *Fetcher) lineBuilder(ctx context.Context) (chan<- PosLine, <-chan Line)
 // Get returns channel for yielding lines. Channel will be closed when no more lines to send
 // Client should close context when no more lines needed
 func (f *Fetcher) Get(ctx context.Context, from Pos) <-chan Line {
+	return f.get(ctx, from, nil)
+}
+
+// get yields lines the same way as Get, except lines for which skip returns true,
+// they are not decoded and filtered at all
+func (f *Fetcher) get(ctx context.Context, from Pos, skip func([]byte) bool) <-chan Line {
 	ret := make(chan Line, 500)
 	startFrom, err := f.findLine(from.Offset)
 	if err == io.EOF {
@@ -234,6 +328,13 @@ func (f *Fetcher) Get(ctx context.Context, from Pos) <-chan Line {
 		from.Line = f.resolveLine(from.Offset)
 	}
 	f.lock.Lock()
+	if f.dedup != dedupOff && skip == nil {
+		var before LineNo
+		startFrom, before = f.runStart(startFrom, &deduper{mode: f.dedup})
+		if from.Line >= 0 {
+			from.Line -= before
+		}
+	}
 	f.seek(startFrom)
 	var wg sync.WaitGroup
 	feeder, lines := f.lineBuilder(ctx)
@@ -246,10 +347,12 @@ func (f *Fetcher) Get(ctx context.Context, from Pos) <-chan Line {
 			if len(str) == 0 && err == io.EOF {
 				return
 			}
-			select {
-			case feeder <- PosLine{b: str, Pos: Pos{lineNum, pos}}:
-			case <-ctx.Done():
-				return
+			if skip == nil || !skip(str) {
+				select {
+				case feeder <- PosLine{b: str, Pos: Pos{lineNum, pos}}:
+				case <-ctx.Done():
+					return
+				}
 			}
 			if err == io.EOF {
 				return
@@ -290,14 +393,22 @@ func (f *Fetcher) Get(ctx context.Context, from Pos) <-chan Line {
 	return ret
 }
 
+// skipFunc returns function telling that raw line doesn't match, so it is not decoded at all.
+// Only lines without escape sequences are checked, others look differently after decoding
+func skipFunc(searchFunc filters.SearchFunc) func([]byte) bool {
+	return func(b []byte) bool {
+		return ansi.IsPlain(b) && searchFunc(b) == nil
+	}
+}
+
 // Search returns position of next matching search
 func (f *Fetcher) Search(ctx context.Context, from Pos, searchFunc filters.SearchFunc) (pos Pos) {
 	defer logging.Timeit("Searching")()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	reader := f.Get(ctx, from)
+	reader := f.get(ctx, from, skipFunc(searchFunc))
 	for l := range reader {
-		if searchFunc(l.Str.Runes) != nil {
+		if searchFunc(l.Text) != nil {
 			return l.Pos
 		}
 	}
@@ -323,9 +434,9 @@ func (f *Fetcher) SearchBack(ctx context.Context, from Pos, searchFunc filters.S
 	defer logging.Timeit("Back-Searching")()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	reader := f.GetBack(ctx, from)
+	reader := f.getBack(ctx, from, skipFunc(searchFunc))
 	for l := range reader {
-		if searchFunc(l.Str.Runes) != nil {
+		if searchFunc(l.Text) != nil {
 			return l.Pos
 		}
 	}
@@ -370,6 +481,116 @@ func (f *Fetcher) advanceLines(from Pos) PosLine {
 	}
 }
 
+const (
+	timeLookahead   = 100  // limits lines read after offset looking for a timestamp
+	entryLookbehind = 1000 // limits lines of multi-line entry read back looking for its start
+)
+
+// searchTime returns offset of the first line logged not before t, lines are expected to be ordered by time,
+// as docker logs are. ok is false when there is no such line
+func (f *Fetcher) searchTime(t time.Time) (offset Offset, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	size := int(f.lastOffset()) + 1
+	i := sort.Search(size, func(i int) bool {
+		lt, _, ok := f.timeAfter(Offset(i))
+		return !ok || !lt.Before(t)
+	})
+	if i == size {
+		return POS_UNKNOWN, false
+	}
+
+	lt, offset, ok := f.timeAfter(Offset(i))
+	if !ok || lt.Before(t) {
+		return POS_UNKNOWN, false
+	}
+
+	return f.entryStart(offset), true
+}
+
+// resolveOffset returns offset of the entry, which raw line at offset in file of version belongs to.
+// Offset is moved by history inserted since, ok is false when file was cleared
+func (f *Fetcher) resolveOffset(offset Offset, version int64) (Offset, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	n, ok := f.reader.Moved(version)
+	if !ok {
+		return POS_UNKNOWN, false
+	}
+
+	return f.entryStart(offset + Offset(n)), true
+}
+
+// entryStart returns offset of the multi-line entry, which raw line at offset belongs to, when grouping
+// is enabled. Continuation lines are timestamped by docker as well, so they are found by time
+func (f *Fetcher) entryStart(offset Offset) Offset {
+	if !f.grouping {
+		return offset
+	}
+
+	f.seek(offset)
+	line, _, err := f.readRawLine()
+	if err != nil && len(line) == 0 {
+		return offset
+	}
+	for i := 0; i < entryLookbehind && offset > 0; i++ {
+		start := f.lineStartBefore(offset)
+		f.seek(start)
+		prev, _, _ := f.readRawLine()
+		if !multiline.Continues(prev, line) {
+			break
+		}
+		offset, line = start, prev
+	}
+
+	return offset
+}
+
+// lineStartBefore returns offset of the raw line ending right before offset
+func (f *Fetcher) lineStartBefore(offset Offset) Offset {
+	buf := make([]byte, 4096)
+	end := int64(offset) - 1 // newline of the line
+	for end > 0 {
+		from := end - int64(len(buf))
+		if from < 0 {
+			from = 0
+		}
+		n, _ := f.reader.ReadAt(buf[:end-from], from)
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i != -1 {
+			return Offset(from) + Offset(i) + 1
+		}
+		end = from
+	}
+
+	return 0
+}
+
+// timeAfter returns timestamp and offset of the first line with timestamp starting at offset or after it
+func (f *Fetcher) timeAfter(offset Offset) (time.Time, Offset, bool) {
+	if offset > 0 {
+		f.seek(offset - 1)
+		if _, _, err := f.readRawLine(); err != nil {
+			return time.Time{}, POS_UNKNOWN, false
+		}
+	} else {
+		f.seek(0)
+	}
+
+	for i := 0; i < timeLookahead; i++ {
+		b, start, err := f.readRawLine()
+		if err != nil {
+			break
+		}
+		if t, n := timestamp.ParseBytes(b); n != 0 {
+			return t, start, true
+		}
+	}
+
+	return time.Time{}, POS_UNKNOWN, false
+}
+
 func (f *Fetcher) lastOffset() Offset {
 	stat, err := f.reader.Stat()
 	if err != nil {
@@ -389,10 +610,16 @@ func (f *Fetcher) lastWROffset() Offset {
 const fetchBackStep = 64 * 1024
 
 func (f *Fetcher) GetBack(ctx context.Context, fromPos Pos) <-chan Line {
+	return f.getBack(ctx, fromPos, nil)
+}
+
+// getBack yields lines the same way as GetBack, except lines for which skip returns true
+func (f *Fetcher) getBack(ctx context.Context, fromPos Pos, skip func([]byte) bool) <-chan Line {
 	// f.lock.Lock()
 	ret := make(chan Line, 500)
 	tmpLines := make([]PosLine, fetchBackStep/20) // Presuming, that average line > 20 cols. Otherwise - append will increase underlying array
 	var l Line
+	var ok bool
 	var lineOffset Offset
 	var err error
 	// Determine if seeking from the end
@@ -401,11 +628,25 @@ func (f *Fetcher) GetBack(ctx context.Context, fromPos Pos) <-chan Line {
 	}
 	lineAssign := fromPos.Line
 	from := fromPos.Offset
+	dd := deduper{mode: f.dedup}
+	var runKey, runLastTime string // run of duplicates following fromPos, it is continued by the first line
+	var runLines int
+	if f.dedup != dedupOff && skip == nil && from >= 0 {
+		f.lock.Lock()
+		runKey, runLines, runLastTime = f.runAfter(from, &dd)
+		f.lock.Unlock()
+	}
 	go func(lineAssign LineNo) {
 		// defer f.lock.Unlock()
 		defer close(ret)
 		for {
 			if from < 0 {
+				if l, ok := dd.flush(); ok {
+					select {
+					case ret <- l:
+					case <-ctx.Done():
+					}
+				}
 				return
 			}
 			tmpLines = tmpLines[:0]
@@ -437,10 +678,20 @@ func (f *Fetcher) GetBack(ctx context.Context, fromPos Pos) <-chan Line {
 					lineAssign--
 					// logging.Debug("assigned line", tmpLines[i].Line)
 				}
+				if skip != nil && skip(tmpLines[i].b) {
+					continue
+				}
 				l = f.filteredLine(tmpLines[i])
 				if l.Pos.Line == POS_FILTERED_OUT { // filtered out
 					continue
 				}
+				if runLines > 0 && dd.key(l) == runKey {
+					l.Repeated, l.LastTime = runLines+1, runLastTime
+				}
+				runLines = 0
+				if l, ok = dd.push(l, true); !ok {
+					continue // collapsed into following one
+				}
 				select {
 				case ret <- l: // TODO: paralellize
 				case <-ctx.Done():
@@ -501,9 +752,18 @@ func (f *Fetcher) resolveLine(o Offset) LineNo {
 	return POS_UNKNOWN
 }
 
+// switchDedup cycles collapsing of duplicate lines: off, identical lines, similar lines
+func (f *Fetcher) switchDedup() dedupMode {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.dedup = (f.dedup + 1) % dedupMode(len(dedupModeNames))
+	return f.dedup
+}
+
 func (f *Fetcher) removeLastFilter() bool {
 	if len(f.filters) > 0 {
 		f.filters = f.filters[:len(f.filters)-1]
+		f.filtersVersion++
 		return true
 	}
 	return false
@@ -0,0 +1,206 @@
+package dlog
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/memfile"
+)
+
+var benchSize = flag.Int("benchsize", 1<<30, "size of log buffer used by benchmarks, bytes")
+
+const benchNeedle = "needle-in-the-last-line"
+
+var benchData struct {
+	styled bool
+	b      []byte
+}
+
+// benchLogs returns benchSize bytes of log lines, styled ones have colored level,
+// only the last line contains benchNeedle
+func benchLogs(b *testing.B, styled bool) []byte {
+	if benchData.b != nil && benchData.styled == styled {
+		return benchData.b
+	}
+	benchData.b = nil // previous buffer is released before making the new one
+
+	level := []byte("INFO")
+	if styled {
+		level = []byte("\x1b[32mINFO\x1b[0m")
+	}
+	data := make([]byte, 0, *benchSize+256)
+	for i := 0; len(data) < *benchSize; i++ {
+		data = append(data, "2022-07-14T10:15:42.123456789Z "...)
+		data = append(data, level...)
+		data = append(data, " request "...)
+		data = strconv.AppendInt(data, int64(i), 10)
+		data = append(data, " handled in 12ms user=alice path=/api/v1/items status=200\n"...)
+	}
+	data = append(data, "2022-07-14T10:15:43.000000000Z ERROR "+benchNeedle+"\n"...)
+
+	benchData.styled, benchData.b = styled, data
+	b.ResetTimer()
+
+	return data
+}
+
+func benchFetcher(ctx context.Context, b *testing.B, styled bool) *Fetcher {
+	data := benchLogs(b, styled)
+
+	return NewFetcher(ctx, memfile.New(data[:len(data):len(data)]))
+}
+
+// BenchmarkSearch searches through the whole buffer, on raw bytes where lines have no escape sequences.
+// Search with skip=false decodes every line, as it was before raw lines were checked
+func BenchmarkSearch(b *testing.B) {
+	for _, styled := range []bool{false, true} {
+		for _, skip := range []bool{true, false} {
+			b.Run("styled="+strconv.FormatBool(styled)+"/skip="+strconv.FormatBool(skip), func(b *testing.B) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				f := benchFetcher(ctx, b, styled)
+				searchFunc, err := filters.GetSearchFunc(filters.CaseSensitive, []rune(benchNeedle))
+				if err != nil {
+					b.Fatal(err)
+				}
+				search := f.Search
+				if !skip {
+					search = func(ctx context.Context, from Pos, searchFunc filters.SearchFunc) Pos {
+						ctx, cancel := context.WithCancel(ctx)
+						defer cancel()
+						for l := range f.get(ctx, from, nil) {
+							if searchFunc(l.Text) != nil {
+								return l.Pos
+							}
+						}
+						return POS_NOT_FOUND
+					}
+				}
+				b.SetBytes(int64(len(benchData.b)))
+
+				for i := 0; i < b.N; i++ {
+					if pos := search(ctx, Pos{0, 0}, searchFunc); pos == POS_NOT_FOUND {
+						b.Fatal("needle is not found")
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkScroll fetches screen of lines from positions moving one line down, as scrolling does
+func BenchmarkScroll(b *testing.B) {
+	const screen = 50
+	for _, cached := range []bool{false, true} {
+		b.Run("cached="+strconv.FormatBool(cached), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			f := benchFetcher(ctx, b, true)
+			if !cached {
+				f.cache = nil
+			}
+
+			var offsets []Offset
+			getCtx, getCancel := context.WithCancel(ctx)
+			for l := range f.Get(getCtx, Pos{0, 0}) {
+				if offsets = append(offsets, l.Offset); len(offsets) == 1000 {
+					break
+				}
+			}
+			getCancel()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				getCtx, getCancel := context.WithCancel(ctx)
+				n := 0
+				for range f.Get(getCtx, Pos{POS_UNKNOWN, offsets[i%len(offsets)]}) {
+					if n++; n == screen {
+						break
+					}
+				}
+				getCancel()
+			}
+		})
+	}
+}
+
+func TestSearchTime(t *testing.T) {
+	data := "2022-07-14T10:00:00Z start\n" +
+		"2022-07-14T10:00:01Z panic: boom\n" +
+		"2022-07-14T10:00:02Z goroutine 1 [running]:\n" +
+		"2022-07-14T10:00:02Z main.main()\n" +
+		"2022-07-14T10:00:03Z \t/app/main.go:5 +0x1d\n" +
+		"2022-07-14T10:00:04Z done\n"
+	at := func(line string) Offset { return Offset(strings.Index(data, line)) }
+
+	tests := []struct {
+		time     string
+		grouping bool
+		want     Offset
+		wantOk   bool
+	}{
+		{"2022-07-14T09:00:00Z", true, 0, true},
+		{"2022-07-14T10:00:01Z", true, at("2022-07-14T10:00:01Z"), true},
+		{"2022-07-14T10:00:02Z", true, at("2022-07-14T10:00:01Z"), true},
+		{"2022-07-14T10:00:03Z", true, at("2022-07-14T10:00:01Z"), true},
+		{"2022-07-14T10:00:03Z", false, at("2022-07-14T10:00:03Z"), true},
+		{"2022-07-14T10:00:04Z", true, at("2022-07-14T10:00:04Z"), true},
+		{"2022-07-14T11:00:00Z", true, POS_UNKNOWN, false},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i, tt := range tests {
+		f := NewFetcher(ctx, memfile.New([]byte(data)))
+		f.grouping = tt.grouping
+		tm, err := time.Parse(time.RFC3339, tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, ok := f.searchTime(tm); got != tt.want || ok != tt.wantOk {
+			t.Errorf("test %d, searchTime(%s):\ngot  %d, %v\nwant %d, %v", i, tt.time, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestResolveOffset(t *testing.T) {
+	data := "2022-07-14T10:00:01Z panic: boom\n" +
+		"2022-07-14T10:00:02Z goroutine 1 [running]:\n" +
+		"2022-07-14T10:00:04Z done\n"
+	history := "2022-07-14T09:00:00Z start\n"
+	at := func(line string) Offset { return Offset(strings.Index(data, line)) }
+
+	tests := []struct {
+		step   func(file *memfile.File)
+		offset Offset
+		want   Offset
+		wantOk bool
+	}{
+		{func(*memfile.File) {}, at("2022-07-14T10:00:04Z"), at("2022-07-14T10:00:04Z"), true},
+		{func(*memfile.File) {}, at("2022-07-14T10:00:02Z"), 0, true},
+		{func(file *memfile.File) { _, _ = file.Insert([]byte(history)) }, at("2022-07-14T10:00:02Z"), Offset(len(history)), true},
+		{func(file *memfile.File) { _, _ = file.Insert([]byte(history)) }, at("2022-07-14T10:00:04Z"),
+			Offset(len(history)) + at("2022-07-14T10:00:04Z"), true},
+		{func(file *memfile.File) { file.Clear(); _, _ = file.Write([]byte(data)) }, at("2022-07-14T10:00:04Z"), POS_UNKNOWN, false},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i, tt := range tests {
+		file := memfile.New([]byte(data))
+		f := NewFetcher(ctx, file)
+		f.grouping = true
+		version := file.Version()
+		tt.step(file)
+
+		if got, ok := f.resolveOffset(tt.offset, version); got != tt.want || ok != tt.wantOk {
+			t.Errorf("test %d, resolveOffset(%d):\ngot  %d, %v\nwant %d, %v", i, tt.offset, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package fields parses structured log payloads into ordered key/value pairs
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+type Kind uint8
+
+const (
+	KindString Kind = iota
+	KindNumber
+	KindBool
+	KindNull
+	KindObject
+)
+
+type Field struct {
+	Key   string
+	Value string // strings are unquoted, objects and arrays kept as compact JSON
+	Kind  Kind
+}
+
+// Fields keeps order of keys as they appear in the payload
+type Fields []Field
+
+// Get returns first field with given key
+func (f Fields) Get(key string) (Field, bool) {
+	for _, field := range f {
+		if field.Key == key {
+			return field, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// IsJSON cheaply checks if payload looks like JSON object, without parsing it
+func IsJSON(b []byte) bool {
+	b = bytes.TrimSpace(b)
+
+	return len(b) >= 2 && b[0] == '{' && b[len(b)-1] == '}'
+}
+
+// ParseJSON parses top level keys of JSON object, false if b is not an object
+func ParseJSON(b []byte) (Fields, bool) {
+	if !IsJSON(b) {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, false
+	}
+
+	var fields Fields
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, false
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false
+		}
+		fields = append(fields, jsonField(key, raw))
+	}
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('}') {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+func jsonField(key string, raw json.RawMessage) Field {
+	f := Field{Key: key, Value: string(raw)}
+	switch raw[0] {
+	case '"':
+		f.Kind = KindString
+		if err := json.Unmarshal(raw, &f.Value); err != nil {
+			f.Value = string(raw)
+		}
+	case 't', 'f':
+		f.Kind = KindBool
+	case 'n':
+		f.Kind = KindNull
+	case '{', '[':
+		f.Kind = KindObject
+		var b bytes.Buffer
+		if err := json.Compact(&b, raw); err == nil {
+			f.Value = b.String()
+		}
+	default:
+		f.Kind = KindNumber
+	}
+
+	return f
+}
+
+// Lookup returns field by path of keys, nested JSON objects are descended into
+func (f Fields) Lookup(path []string) (Field, bool) {
+	if len(path) == 0 {
+		return Field{}, false
+	}
+
+	field, ok := f.Get(path[0])
+	if !ok || len(path) == 1 {
+		return field, ok
+	}
+
+	nested, ok := ParseJSON([]byte(field.Value))
+	if !ok {
+		return Field{}, false
+	}
+
+	return nested.Lookup(path[1:])
+}
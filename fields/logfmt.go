@@ -0,0 +1,136 @@
+package fields
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dimcz/dlog/timestamp"
+)
+
+// Parse parses payload of the line, docker timestamp is skipped. JSON objects are tried first,
+// then logfmt key=value pairs
+func Parse(line []rune) (Fields, bool) {
+	_, n := timestamp.Parse(line)
+
+	return parsePayload([]byte(string(line[n:])))
+}
+
+// ParseBytes is Parse of line encoded as UTF-8
+func ParseBytes(line []byte) (Fields, bool) {
+	_, n := timestamp.ParseBytes(line)
+
+	return parsePayload(line[n:])
+}
+
+func parsePayload(payload []byte) (Fields, bool) {
+	if f, ok := ParseJSON(payload); ok {
+		return f, true
+	}
+
+	return ParseLogfmt(string(payload))
+}
+
+// ParseLogfmt extracts key=value pairs, values may be double-quoted. Words without `=`
+// are skipped, so lines mixing plain text and pairs are supported. False if no pairs found
+func ParseLogfmt(s string) (Fields, bool) {
+	var fields Fields
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' && s[i] != '"' {
+			i++
+		}
+		if i == len(s) || s[i] != '=' || i == start {
+			i = skipWord(s, i)
+			continue
+		}
+
+		key := s[start:i]
+		i++ // skipping =
+
+		var value string
+		quoted := i < len(s) && s[i] == '"'
+		if quoted {
+			end := closingQuote(s, i)
+			unquoted, err := strconv.Unquote(s[i:end])
+			if err != nil {
+				unquoted = strings.Trim(s[i:end], "\"")
+			}
+			value, i = unquoted, end
+		} else {
+			end := i
+			for end < len(s) && s[end] != ' ' && s[end] != '\t' {
+				end++
+			}
+			value, i = s[i:end], end
+		}
+
+		if isKey(key) {
+			fields = append(fields, logfmtField(key, value, quoted))
+		}
+	}
+
+	return fields, len(fields) != 0
+}
+
+// skipWord moves to the end of the word, quoted parts are skipped as a whole
+func skipWord(s string, i int) int {
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		if s[i] == '"' {
+			i = closingQuote(s, i)
+			continue
+		}
+		i++
+	}
+
+	return i
+}
+
+// closingQuote returns index following closing quote of string starting at i
+func closingQuote(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+
+	return len(s)
+}
+
+func isKey(key string) bool {
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.@/", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func logfmtField(key, value string, quoted bool) Field {
+	f := Field{Key: key, Value: value, Kind: KindString}
+	if quoted {
+		return f
+	}
+
+	switch value {
+	case "true", "false":
+		f.Kind = KindBool
+	case "null", "nil":
+		f.Kind = KindNull
+	default:
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			f.Kind = KindNumber
+		}
+	}
+
+	return f
+}
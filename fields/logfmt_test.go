@@ -0,0 +1,37 @@
+package fields
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   Fields
+		wantOk bool
+	}{
+		{`level=info msg=started`, Fields{{"level", "info", KindString}, {"msg", "started", KindString}}, true},
+		{`msg="hello world" n=42`, Fields{{"msg", "hello world", KindString}, {"n", "42", KindNumber}}, true},
+		{`q="say \"hi\""`, Fields{{"q", `say "hi"`, KindString}}, true},
+		{`ok=true user=null err=nil`, Fields{{"ok", "true", KindBool}, {"user", "null", KindNull}, {"err", "nil", KindNull}}, true},
+		{`n="42"`, Fields{{"n", "42", KindString}}, true},
+		{`GET /items done status=200`, Fields{{"status", "200", KindNumber}}, true},
+		{`http.path=/api trace-id=a1 @t=1`, Fields{{"http.path", "/api", KindString}, {"trace-id", "a1", KindString}, {"@t", "1", KindNumber}}, true},
+		{`empty= next=1`, Fields{{"empty", "", KindString}, {"next", "1", KindNumber}}, true},
+		{`"quoted=word" a=1`, Fields{{"a", "1", KindNumber}}, true},
+		{`unterminated="abc`, Fields{{"unterminated", "abc", KindString}}, true},
+		{`a==b`, Fields{{"a", "=b", KindString}}, true},
+		{`x+y=1`, nil, false},
+		{`=value`, nil, false},
+		{`plain text only`, nil, false},
+		{``, nil, false},
+	}
+
+	for i, tt := range tests {
+		got, ok := ParseLogfmt(tt.s)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOk {
+			t.Errorf("test %d, ParseLogfmt(%q):\ngot  %v, %v\nwant %v, %v", i, tt.s, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
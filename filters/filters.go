@@ -2,14 +2,19 @@ package filters
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"os"
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/dimcz/dlog/fields"
+	"github.com/dimcz/dlog/level"
 	"github.com/dimcz/dlog/logging"
-	"github.com/dimcz/dlog/runes"
+	"github.com/dimcz/dlog/timestamp"
 	"github.com/dimcz/dlog/utils"
 
 	"github.com/nsf/termbox-go"
@@ -29,7 +34,7 @@ const (
 
 //goland:noinspection GoUnusedType
 type filter interface {
-	takeAction(str []rune, currentAction FilterResult) FilterResult
+	takeAction(str []byte, currentAction FilterResult) FilterResult
 }
 
 type SearchType struct {
@@ -47,6 +52,12 @@ var RegEx = SearchType{
 	Color: termbox.ColorRed,
 	Name:  "RegEx",
 }
+
+// Field matches lines by predicates on fields of JSON or logfmt payload, see ParsePredicate
+var Field = SearchType{
+	Color: termbox.ColorCyan,
+	Name:  "Field",
+}
 var SearchTypeMap map[uint8]SearchType
 
 type FilterAction uint8
@@ -75,17 +86,18 @@ var FilterActionMap = map[rune]FilterAction{
 func init() {
 	SearchTypeMap = make(map[uint8]SearchType)
 	// Should maintain order, otherwise history will be corrupted.
-	for i, r := range []*SearchType{&CaseSensitive, &RegEx} {
+	for i, r := range []*SearchType{&CaseSensitive, &RegEx, &Field} {
 		r.ID = uint8(i)
 		SearchTypeMap[r.ID] = *r
 	}
 
 }
 
-// SearchFunc Follows regex return value pattern. nil if not found, slice of range if found
+// SearchFunc Follows regex return value pattern. nil if not found, slice of range if found.
+// It runs on text of line encoded as UTF-8, so range is in bytes, IndexAll maps it to runes shown.
 // Filter does not really need it, but highlighting also must search and requires it
-type SearchFunc func(sub []rune) []int
-type ActionFunc func(str []rune, currentAction FilterResult) FilterResult
+type SearchFunc func(str []byte) []int
+type ActionFunc func(str []byte, currentAction FilterResult) FilterResult
 
 type Filter struct {
 	sub        []rune
@@ -123,25 +135,54 @@ func NewFilter(sub []rune, action FilterAction, searchType SearchType) (*Filter,
 	}, nil
 }
 
+// NewLevelFilter returns intersect filter keeping lines with detected level at least min.
+// Lines without detected level, like plain output or continuation of entries, are kept
+func NewLevelFilter(min level.Level) *Filter {
+	ff := func(str []byte) []int {
+		if l := level.DetectBytes(str); l != level.Unknown && l < min {
+			return nil
+		}
+		return []int{0, len(str)}
+	}
+
+	return &Filter{
+		sub:        []rune(">=" + min.String()),
+		st:         CaseSensitive,
+		Action:     FilterIntersect,
+		TakeAction: buildIntersectionFunc(ff),
+	}
+}
+
 func GetSearchFunc(searchType SearchType, sub []rune) (SearchFunc, error) {
 	var ff SearchFunc
 	switch searchType {
 	case CaseSensitive:
-		subLen := len(sub)
-		ff = func(str []rune) []int {
-			i := runes.Index(str, sub)
+		b := []byte(string(sub))
+		ff = func(str []byte) []int {
+			i := bytes.Index(str, b)
 			if i == -1 {
 				return nil
 			}
-			return []int{i, i + subLen}
+			return []int{i, i + len(b)}
 		}
 	case RegEx:
 		re, err := regexp.Compile(string(sub))
 		if err != nil {
 			return nil, ErrBadFilterDefinition
 		}
-		ff = func(str []rune) []int {
-			return re.FindStringIndex(string(str))
+		ff = re.FindIndex
+	case Field:
+		pred, err := ParsePredicate(string(sub))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadFilterDefinition, err)
+		}
+		ff = func(str []byte) []int {
+			parsed, ok := fields.ParseBytes(str)
+			if !ok || !pred(parsed) {
+				return nil
+			}
+			_, n := timestamp.ParseBytes(str)
+			return []int{n, len(str)}
 		}
 	default:
 		return nil, ErrBadFilterDefinition
@@ -150,35 +191,79 @@ func GetSearchFunc(searchType SearchType, sub []rune) (SearchFunc, error) {
 	return ff, nil
 }
 
-func IndexAll(searchFunc SearchFunc, runestack []rune) (indices [][]int) {
+// IndexAllFunc returns byte ranges of all matches in str, the same way as regexp FindAllIndex does
+type IndexAllFunc func(str []byte) [][]int
+
+// GetIndexAllFunc returns function finding all matches for highlighting. Regular expression is matched
+// on the whole line at once, so anchors like ^ match only at its start, not after previous match
+func GetIndexAllFunc(searchType SearchType, sub []rune) (IndexAllFunc, error) {
+	if searchType == RegEx {
+		re, err := regexp.Compile(string(sub))
+		if err != nil {
+			return nil, ErrBadFilterDefinition
+		}
+		return func(str []byte) [][]int { return re.FindAllIndex(str, -1) }, nil
+	}
+
+	searchFunc, err := GetSearchFunc(searchType, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(str []byte) (indices [][]int) {
+		for i := 0; i < len(str); {
+			ret := searchFunc(str[i:])
+			if ret == nil {
+				break
+			}
+			start, end := ret[0]+i, ret[1]+i
+			indices = append(indices, []int{start, end})
+			if end > start {
+				i = end
+				continue
+			}
+			_, size := utf8.DecodeRune(str[end:])
+			i = end + size
+		}
+		return
+	}, nil
+}
+
+// IndexAll returns ranges of all matches in runes, as rune indices, the way they are highlighted.
+// Empty matches are skipped
+func IndexAll(indexAll IndexAllFunc, runestack []rune) (indices [][]int) {
 	if len(runestack) == 0 {
 		return
 	}
-	var i int
-	var ret []int
-	f := 0
+	str := []byte(string(runestack))
 	indices = make([][]int, 0, 1)
-	for {
-		ret = searchFunc(runestack[i:])
-		f++
-		if ret == nil {
-			break
-		} else {
-			ret[0] = ret[0] + i
-			ret[1] = ret[1] + i
-			indices = append(indices, ret)
-			i = i + ret[1]
-		}
-		if i >= len(runestack) {
-			break
+	for _, rng := range indexAll(str) {
+		if rng[1] > rng[0] {
+			indices = append(indices, rng)
 		}
 	}
+	toRuneIndices(str, indices)
 
 	return
 }
 
+// toRuneIndices converts sorted byte offsets of ranges in str to rune indices
+func toRuneIndices(str []byte, ranges [][]int) {
+	b, r := 0, 0
+	for _, rng := range ranges {
+		for k, offset := range rng {
+			for b < offset {
+				_, size := utf8.DecodeRune(str[b:])
+				b += size
+				r++
+			}
+			rng[k] = r
+		}
+	}
+}
+
 func buildUnionFunc(searchFunc SearchFunc) ActionFunc {
-	return func(str []rune, currentAction FilterResult) FilterResult {
+	return func(str []byte, currentAction FilterResult) FilterResult {
 		if currentAction == FilterHighlighted {
 			return FilterHighlighted
 		}
@@ -195,7 +280,7 @@ func buildUnionFunc(searchFunc SearchFunc) ActionFunc {
 }
 
 func buildIntersectionFunc(searchFunc SearchFunc) ActionFunc {
-	return func(str []rune, currentAction FilterResult) FilterResult {
+	return func(str []byte, currentAction FilterResult) FilterResult {
 		if currentAction == FilterHighlighted {
 			return FilterHighlighted
 		}
@@ -211,7 +296,7 @@ func buildIntersectionFunc(searchFunc SearchFunc) ActionFunc {
 }
 
 func buildExcludeFunc(searchFunc SearchFunc) ActionFunc {
-	return func(str []rune, currentAction FilterResult) FilterResult {
+	return func(str []byte, currentAction FilterResult) FilterResult {
 		if currentAction == FilterHighlighted {
 			return FilterHighlighted
 		}
@@ -227,7 +312,7 @@ func buildExcludeFunc(searchFunc SearchFunc) ActionFunc {
 }
 
 func buildHighlightFunc(searchFunc SearchFunc) ActionFunc {
-	return func(str []rune, currentAction FilterResult) FilterResult {
+	return func(str []byte, currentAction FilterResult) FilterResult {
 		if currentAction == FilterHighlighted {
 			return FilterHighlighted
 		}
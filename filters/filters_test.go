@@ -0,0 +1,88 @@
+package filters
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dimcz/dlog/level"
+)
+
+func TestSearchFunc(t *testing.T) {
+	tests := []struct {
+		searchType SearchType
+		sub        string
+		str        string
+		want       []int
+	}{
+		{CaseSensitive, "мир", "привет мир", []int{13, 19}},
+		{CaseSensitive, "Мир", "привет мир", nil},
+		{CaseSensitive, "日本", "ログ 日本語", []int{7, 13}},
+		{RegEx, `м.р`, "привет мир", []int{13, 19}},
+		{RegEx, `\p{Han}+`, "ログ 日本語 text", []int{7, 16}},
+		{RegEx, `ошибка$`, "ошибка не ошибка", []int{18, 30}},
+		{Field, `.msg == "готово"`, `2022-07-14T10:15:42Z {"msg":"готово"}`, []int{21, 43}},
+		{Field, `.msg == "готово"`, `{"msg":"ошибка"}`, nil},
+	}
+	for i, test := range tests {
+		searchFunc, err := GetSearchFunc(test.searchType, []rune(test.sub))
+		if err != nil {
+			t.Fatalf("test %d: %s", i, err)
+		}
+		if got := searchFunc([]byte(test.str)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("test %d, %s %q in %q:\ngot  %v\nwant %v", i, test.searchType.Name, test.sub, test.str, got, test.want)
+		}
+	}
+}
+
+func TestIndexAll(t *testing.T) {
+	tests := []struct {
+		searchType SearchType
+		sub        string
+		str        string
+		want       [][]int
+	}{
+		{CaseSensitive, "ab", "ab ab ab", [][]int{{0, 2}, {3, 5}, {6, 8}}},
+		{CaseSensitive, "мир", "мир, миру мир", [][]int{{0, 3}, {5, 8}, {10, 13}}},
+		{CaseSensitive, "é", "café é", [][]int{{3, 4}, {5, 6}}},
+		{CaseSensitive, "x", "日本語", [][]int{}},
+		{RegEx, `\d+`, "ответ 42 за 7мс", [][]int{{6, 8}, {12, 13}}},
+		{RegEx, `😀+`, "a😀😀b😀", [][]int{{1, 3}, {4, 5}}},
+		{RegEx, `x*`, "日x本", [][]int{{1, 2}}},
+		{RegEx, `^a`, "aaa", [][]int{{0, 1}}},
+		{RegEx, `a$`, "aaa", [][]int{{2, 3}}},
+		{RegEx, `\ba\w`, "abab ab", [][]int{{0, 2}, {5, 7}}},
+		{RegEx, `(?m)^a`, "ab\nab", [][]int{{0, 1}, {3, 4}}},
+		{Field, `.level == "error"`, `{"level":"error"}`, [][]int{{0, 17}}},
+	}
+	for i, test := range tests {
+		indexAll, err := GetIndexAllFunc(test.searchType, []rune(test.sub))
+		if err != nil {
+			t.Fatalf("test %d: %s", i, err)
+		}
+		if got := IndexAll(indexAll, []rune(test.str)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("test %d, %s %q in %q:\ngot  %v\nwant %v", i, test.searchType.Name, test.sub, test.str, got, test.want)
+		}
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	tests := []struct {
+		min  level.Level
+		str  string
+		want FilterResult
+	}{
+		{level.Warn, "2022-07-14T10:15:42Z ERROR failed", FilterIncluded},
+		{level.Warn, "2022-07-14T10:15:42Z WARN slow", FilterIncluded},
+		{level.Warn, "2022-07-14T10:15:42Z INFO started", FilterExcluded},
+		{level.Warn, `{"level":"debug"}`, FilterExcluded},
+		{level.Warn, "plain output", FilterIncluded},
+		{level.Trace, "plain output", FilterIncluded},
+		{level.Trace, "2022-07-14T10:15:42Z TRACE entered", FilterIncluded},
+	}
+	for i, test := range tests {
+		f := NewLevelFilter(test.min)
+		if got := f.TakeAction([]byte(test.str), FilterNoaction); got != test.want {
+			t.Errorf("test %d, level >= %s of %q:\ngot  %v\nwant %v", i, test.min, test.str, got, test.want)
+		}
+	}
+}
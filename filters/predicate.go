@@ -0,0 +1,271 @@
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dimcz/dlog/fields"
+)
+
+// Predicate tests fields parsed from JSON or logfmt payload of the line
+type Predicate func(f fields.Fields) bool
+
+// ParsePredicate compiles field expressions like
+//
+//	.level == "error"
+//	.latency_ms > 500 && has(.trace_id)
+//	!has(.user) || .http.status =~ "^5"
+//
+// Supported operators are == != > >= < <= =~, numbers are compared numerically
+func ParsePredicate(expr string) (Predicate, error) {
+	p := &predicateParser{s: expr}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos != len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+
+	return pred, nil
+}
+
+type predicateParser struct {
+	s   string
+	pos int
+}
+
+func (p *predicateParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *predicateParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// consume skips token if it is next one in expression
+func (p *predicateParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+
+	return false
+}
+
+func (p *predicateParser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f fields.Fields) bool { return l(f) || right(f) }
+	}
+
+	return left, nil
+}
+
+func (p *predicateParser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f fields.Fields) bool { return l(f) && right(f) }
+	}
+
+	return left, nil
+}
+
+func (p *predicateParser) parseUnary() (Predicate, error) {
+	switch {
+	case p.consume("!"):
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f fields.Fields) bool { return !pred(f) }, nil
+	case p.consume("("):
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return pred, nil
+	case p.consume("has("):
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return func(f fields.Fields) bool {
+			_, ok := f.Lookup(path)
+			return ok
+		}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *predicateParser) parsePath() ([]string, error) {
+	p.skipSpaces()
+	var path []string
+	for p.pos < len(p.s) && p.s[p.pos] == '.' {
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.s) && isIdentChar(rune(p.s[p.pos])) {
+			p.pos++
+		}
+		if start == p.pos {
+			return nil, p.errorf("field name expected")
+		}
+		path = append(path, p.s[start:p.pos])
+	}
+	if len(path) == 0 {
+		return nil, p.errorf("field expected, e.g. .level")
+	}
+
+	return path, nil
+}
+
+func isIdentChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '@'
+}
+
+var comparisonOps = []string{"==", "!=", ">=", "<=", "=~", ">", "<"}
+
+func (p *predicateParser) parseComparison() (Predicate, error) {
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	op := ""
+	for _, o := range comparisonOps {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		// bare field is true when present and not false/null
+		return func(f fields.Fields) bool {
+			field, ok := f.Lookup(path)
+			return ok && field.Kind != fields.KindNull && field.Value != "false"
+		}, nil
+	}
+
+	value, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "=~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(f fields.Fields) bool {
+			field, ok := f.Lookup(path)
+			return ok && re.MatchString(field.Value)
+		}, nil
+	}
+
+	return func(f fields.Fields) bool {
+		field, ok := f.Lookup(path)
+		return ok && compare(field.Value, op, value)
+	}, nil
+}
+
+func (p *predicateParser) parseLiteral() (string, error) {
+	p.skipSpaces()
+	if p.pos == len(p.s) {
+		return "", p.errorf("value expected")
+	}
+
+	if p.s[p.pos] == '"' {
+		end := p.pos + 1
+		for end < len(p.s) && p.s[end] != '"' {
+			if p.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		value, err := strconv.Unquote(p.s[p.pos : end+1])
+		if err != nil {
+			return "", p.errorf("bad string: %s", err)
+		}
+		p.pos = end + 1
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(literalEnds, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("value expected")
+	}
+
+	return p.s[start:p.pos], nil
+}
+
+// literalEnds are characters ending unquoted literal, values containing them are quoted
+const literalEnds = " )&|!=<>"
+
+// compare compares values as numbers when both are numeric, otherwise as strings
+func compare(a, op, b string) bool {
+	var c int
+	af, aErr := strconv.ParseFloat(a, 64)
+	bf, bErr := strconv.ParseFloat(b, 64)
+	switch {
+	case aErr == nil && bErr == nil && af < bf:
+		c = -1
+	case aErr == nil && bErr == nil && af > bf:
+		c = 1
+	case aErr == nil && bErr == nil:
+		c = 0
+	default:
+		c = strings.Compare(a, b)
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+
+	return false
+}
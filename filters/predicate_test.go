@@ -0,0 +1,74 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/dimcz/dlog/fields"
+)
+
+func TestParsePredicate(t *testing.T) {
+	const line = `{"level":"error","latency_ms":750,"user":null,"ok":false,"msg":"a b","http":{"status":503},"id":"0012"}`
+	f, ok := fields.ParseBytes([]byte(line))
+	if !ok {
+		t.Fatal("line is not parsed")
+	}
+
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: `.level == "error"`, want: true},
+		{expr: `.level=="error"`, want: true},
+		{expr: `.level == error`, want: true},
+		{expr: `.level != "error"`, want: false},
+		{expr: `.msg == "a b"`, want: true},
+		{expr: `.msg == "say \"hi\""`, want: false},
+		{expr: `.latency_ms > 500`, want: true},
+		{expr: `.latency_ms > 1000`, want: false},
+		{expr: `.latency_ms >= 750 && .latency_ms <= 750`, want: true},
+		{expr: `.latency_ms < 80`, want: false}, // numeric, not "750" < "80"
+		{expr: `.id == 12`, want: true},
+		{expr: `.id == "12"`, want: true},
+		{expr: `.http.status =~ "^5"`, want: true},
+		{expr: `.http.status=~^4`, want: false},
+		{expr: `has(.user)`, want: true},
+		{expr: `has(.trace_id)`, want: false},
+		{expr: `.user`, want: false},
+		{expr: `.ok`, want: false},
+		{expr: `.level`, want: true},
+		{expr: `!has(.trace_id)`, want: true},
+		{expr: `.a==5&&has(.level)`, want: false},
+		{expr: `.latency_ms==750&&has(.level)`, want: true},
+		{expr: `.level==debug||.latency_ms>500`, want: true},
+		{expr: `.level == "debug" || .level == "error" && .latency_ms < 100`, want: false},
+		{expr: `(.level == "debug" || .level == "error") && .latency_ms > 100`, want: true},
+		{expr: `!(.level == "error")`, want: false},
+		{expr: `.level ==`, wantErr: true},
+		{expr: `.level == && has(.user)`, wantErr: true},
+		{expr: `.level == "error`, wantErr: true},
+		{expr: `(.level == "error"`, wantErr: true},
+		{expr: `has(.user`, wantErr: true},
+		{expr: `level == "error"`, wantErr: true},
+		{expr: `.`, wantErr: true},
+		{expr: `.level == "error" garbage`, wantErr: true},
+		{expr: `.msg =~ "("`, wantErr: true},
+		{expr: ``, wantErr: true},
+	}
+	for i, test := range tests {
+		pred, err := ParsePredicate(test.expr)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d, %q: error expected", i, test.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d, %q: %s", i, test.expr, err)
+			continue
+		}
+		if got := pred(f); got != test.want {
+			t.Errorf("test %d, %q:\ngot  %v\nwant %v", i, test.expr, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package dlog
+
+import (
+	"fmt"
+
+	"github.com/dimcz/dlog/logging"
+
+	"github.com/nsf/termbox-go"
+)
+
+const helpKeysWidth = 28 // keys of binding are padded up to it
+
+type helpRow struct {
+	text    string
+	section bool
+}
+
+// helpView is an overlay listing key bindings by sections, it is generated from keymaps
+type helpView struct {
+	v    *viewer
+	rows []helpRow
+	top  int
+}
+
+// helpRows returns rows describing bindings, section title is added when section changes
+func helpRows[T any](rows []helpRow, bindings []binding[T]) []helpRow {
+	section := ""
+	for i := range bindings {
+		b := &bindings[i]
+		if b.section != section {
+			section = b.section
+			rows = append(rows, helpRow{text: ""}, helpRow{text: section, section: true})
+		}
+		rows = append(rows, helpRow{text: fmt.Sprintf("  %-*s  %s", helpKeysWidth, b.names(), b.help)})
+	}
+
+	return rows
+}
+
+func (v *viewer) showHelp() {
+	rows := helpRows(nil, keymap)
+	rows = helpRows(rows, screenKeymap)
+	rows = helpRows(rows, inputKeymap)
+
+	v.focus = &helpView{v: v, rows: rows[1:]}
+	v.draw()
+}
+
+func (hv *helpView) draw() {
+	hv.v.clear()
+
+	hv.v.printRow(0, "Keys. j/k,f/b: scroll  q: close", termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+
+	for y := 0; y < hv.v.rows-1 && hv.top+y < len(hv.rows); y++ {
+		row := hv.rows[hv.top+y]
+		fg := termbox.ColorDefault
+		if row.section {
+			fg = termbox.ColorGreen | termbox.AttrBold
+		}
+		hv.v.printRow(y+1, row.text, fg, termbox.ColorDefault)
+	}
+
+	logging.LogOnErr(termbox.Flush())
+}
+
+func (hv *helpView) scroll(direction int) {
+	hv.top += direction
+	if hv.top > len(hv.rows)-(hv.v.rows-1) {
+		hv.top = len(hv.rows) - (hv.v.rows - 1)
+	}
+	if hv.top < 0 {
+		hv.top = 0
+	}
+	hv.draw()
+}
+
+func (hv *helpView) processKey(ev termbox.Event) action {
+	if ev.Ch != 0 {
+		switch ev.Ch {
+		case 'q', 'i':
+			return ACTION_RESET_FOCUS
+		case 'j':
+			hv.scroll(+1)
+		case 'k':
+			hv.scroll(-1)
+		case 'f':
+			hv.scroll(hv.v.rows - 1)
+		case 'b':
+			hv.scroll(-(hv.v.rows - 1))
+		case 'g':
+			hv.scroll(-len(hv.rows))
+		case 'G':
+			hv.scroll(len(hv.rows))
+		}
+		return NO_ACTION
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc, termbox.KeyF1:
+		return ACTION_RESET_FOCUS
+	case termbox.KeyArrowDown:
+		hv.scroll(+1)
+	case termbox.KeyArrowUp:
+		hv.scroll(-1)
+	case termbox.KeyPgdn, termbox.KeySpace:
+		hv.scroll(hv.v.rows - 1)
+	case termbox.KeyPgup:
+		hv.scroll(-(hv.v.rows - 1))
+	}
+	return NO_ACTION
+}
+
+func (hv *helpView) processMouse(ev termbox.Event, _, _ int) action {
+	switch ev.Key {
+	case termbox.MouseWheelUp:
+		hv.scroll(-wheelLines)
+	case termbox.MouseWheelDown:
+		hv.scroll(+wheelLines)
+	}
+
+	return NO_ACTION
+}
@@ -0,0 +1,562 @@
+package dlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/multiline"
+	"github.com/dimcz/dlog/timestamp"
+
+	"github.com/nsf/termbox-go"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+const histogramInterval = 2 * time.Second
+
+const (
+	histogramTotalColor   = termbox.ColorBlue
+	histogramMatchedColor = termbox.ColorYellow
+)
+
+// rateBucket counts lines with timestamps within one time step
+type rateBucket struct {
+	total   int
+	matched int    // lines shown with current filters and matching current search
+	offset  Offset // the earliest line of the bucket, to jump to
+}
+
+type histogram struct {
+	buckets  []rateBucket
+	from     time.Time
+	step     time.Duration
+	matching bool // any filter or search is applied, otherwise matched is not counted
+}
+
+// histogramKey identifies state histogram was built for, it is rebuilt when any part changes
+type histogramKey struct {
+	width          int
+	lastOffset     Offset
+	filters        int64 // version of filters
+	filtersEnabled bool
+	search         string
+}
+
+type rateSample struct {
+	t       time.Time
+	offset  Offset
+	matched bool
+}
+
+// maxRateSlots limits time slots kept by rateCounter, slots are merged by pairs when there are more
+const maxRateSlots = 1 << 14
+
+// rateKey identifies lines and matching rateCounter counted, counts are dropped when it changes
+type rateKey struct {
+	version        int64 // of memfile, it is changed by inserting history and clearing
+	filters        int64 // version of filters
+	filtersEnabled bool
+	grouping       bool
+	search         string
+}
+
+// rateCounter counts lines per time slot. It is updated incrementally, only entries appended since
+// the previous count are read. The last entry is not counted, as it may be continued by next lines
+type rateCounter struct {
+	key     rateKey
+	scanned Offset    // start of the last entry, counting is continued from it
+	last    time.Time // timestamp of the last counted line with one
+	step    time.Duration
+	slots   map[int64]*rateBucket // by time divided by step
+}
+
+func (rc *rateCounter) reset(key rateKey) {
+	*rc = rateCounter{key: key, step: time.Millisecond, slots: map[int64]*rateBucket{}}
+}
+
+func (rc *rateCounter) add(s rateSample) {
+	k := s.t.UnixNano() / int64(rc.step)
+	b, ok := rc.slots[k]
+	if !ok {
+		b = &rateBucket{offset: s.offset}
+		rc.slots[k] = b
+	}
+	b.total++
+	if s.matched {
+		b.matched++
+	}
+	if s.offset < b.offset {
+		b.offset = s.offset
+	}
+
+	for len(rc.slots) > maxRateSlots {
+		rc.merge()
+	}
+}
+
+// merge doubles step of slots
+func (rc *rateCounter) merge() {
+	rc.step *= 2
+	slots := make(map[int64]*rateBucket, len(rc.slots)/2+1)
+	for k, b := range rc.slots {
+		if m, ok := slots[k>>1]; ok {
+			m.total += b.total
+			m.matched += b.matched
+			if b.offset < m.offset {
+				m.offset = b.offset
+			}
+			continue
+		}
+		slots[k>>1] = b
+	}
+	rc.slots = slots
+}
+
+// histogram splits time range of counted slots and pending sample into n buckets
+func (rc *rateCounter) histogram(n int, matching bool, pending *rateSample) *histogram {
+	if len(rc.slots) == 0 && pending == nil || n <= 0 {
+		return nil
+	}
+
+	slotTime := func(k int64) time.Time { return time.Unix(0, k*int64(rc.step)) }
+	var from, to time.Time
+	extend := func(t time.Time) {
+		if from.IsZero() || t.Before(from) {
+			from = t
+		}
+		if to.IsZero() || t.After(to) {
+			to = t
+		}
+	}
+	for k := range rc.slots {
+		extend(slotTime(k))
+	}
+	if pending != nil {
+		extend(pending.t)
+	}
+
+	h := &histogram{
+		buckets:  make([]rateBucket, n),
+		from:     from,
+		step:     (to.Sub(from) + time.Duration(n)) / time.Duration(n),
+		matching: matching,
+	}
+	for i := range h.buckets {
+		h.buckets[i].offset = -1
+	}
+	add := func(t time.Time, s rateBucket) {
+		b := &h.buckets[h.index(t)]
+		b.total += s.total
+		b.matched += s.matched
+		if b.offset == -1 || s.offset < b.offset {
+			b.offset = s.offset
+		}
+	}
+	for k, s := range rc.slots {
+		add(slotTime(k), *s)
+	}
+	if pending != nil {
+		s := rateBucket{total: 1, offset: pending.offset}
+		if pending.matched {
+			s.matched = 1
+		}
+		add(pending.t, s)
+	}
+
+	return h
+}
+
+// histogram counts lines appended since the previous call into rc and splits time range of lines into
+// n buckets. Lines without timestamp, like continuation of multi-line entries without grouping, are counted
+// in the bucket of previous line. Lines are read without f.lock, it is taken only to match them with filters
+func (f *Fetcher) histogram(ctx context.Context, rc *rateCounter, n int, search string, searchFunc filters.SearchFunc) *histogram {
+	defer logging.Timeit("histogram")()
+
+	f.lock.RLock()
+	key := rateKey{
+		version:        f.reader.Version(),
+		filters:        f.filtersVersion,
+		filtersEnabled: f.filtersEnabled,
+		grouping:       f.grouping,
+		search:         search,
+	}
+	matching := searchFunc != nil || (f.filtersEnabled && len(f.filters) > 0)
+	f.lock.RUnlock()
+	if rc.key != key || rc.slots == nil {
+		rc.reset(key)
+	}
+
+	sample := func(entry []byte, offset Offset) (rateSample, bool) {
+		if t, _ := timestamp.ParseBytes(entry[:timestampPrefix(entry)]); !t.IsZero() {
+			rc.last = t
+		}
+		if rc.last.IsZero() {
+			return rateSample{}, false
+		}
+		s := rateSample{t: rc.last, offset: offset}
+		if matching {
+			f.lock.RLock()
+			s.matched = f.matches(entry, offset, searchFunc)
+			f.lock.RUnlock()
+		}
+
+		return s, true
+	}
+
+	size := int64(f.lastOffset()) + 1
+	r := bufio.NewReaderSize(io.NewSectionReader(f.reader, int64(rc.scanned), size-int64(rc.scanned)), ChunkSize)
+	var entry, prev []byte
+	offset, entryOffset := rc.scanned, rc.scanned
+	for i := 0; ; i++ {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return nil
+		}
+
+		line, err := r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			break
+		}
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		if entry != nil && key.grouping && multiline.Continues(prev, line) {
+			entry = append(append(entry, '\n'), timestamp.Strip(line)...)
+		} else {
+			if entry != nil {
+				if s, ok := sample(entry, entryOffset); ok {
+					rc.add(s)
+				}
+				rc.scanned = offset
+			}
+			entry, entryOffset = line, offset
+		}
+		prev = line
+		offset += Offset(len(line) + 1)
+		if err != nil {
+			break
+		}
+	}
+
+	last := rc.last
+	var pending *rateSample
+	if entry != nil {
+		if s, ok := sample(entry, entryOffset); ok {
+			pending = &s
+		}
+	}
+	rc.last = last // the last entry is read again by the next call
+	if f.reader.Version() != key.version {
+		rc.reset(rateKey{})
+		return nil // history was inserted meanwhile, lines are counted again by the next call
+	}
+
+	return rc.histogram(n, matching, pending)
+}
+
+// timestampPrefix returns length of the line part which may hold timestamp
+func timestampPrefix(str []byte) int {
+	const maxTimestampLen = 40
+	if len(str) < maxTimestampLen {
+		return len(str)
+	}
+
+	return maxTimestampLen
+}
+
+func (f *Fetcher) matches(str []byte, offset Offset, searchFunc filters.SearchFunc) bool {
+	l := f.filteredLine(PosLine{b: str, Pos: Pos{POS_UNKNOWN, offset}})
+	if l.Pos.Line == POS_FILTERED_OUT {
+		return false
+	}
+	if searchFunc == nil {
+		return true
+	}
+
+	return searchFunc(l.Text) != nil
+}
+
+func (h *histogram) index(t time.Time) int {
+	i := int(t.Sub(h.from) / h.step)
+	if i < 0 {
+		return 0
+	}
+	if i >= len(h.buckets) {
+		return len(h.buckets) - 1
+	}
+
+	return i
+}
+
+func (h *histogram) max() (total, matched int) {
+	for _, b := range h.buckets {
+		if b.total > total {
+			total = b.total
+		}
+		if b.matched > matched {
+			matched = b.matched
+		}
+	}
+
+	return total, matched
+}
+
+func (h *histogram) bucketTime(i int) time.Time {
+	return h.from.Add(time.Duration(i) * h.step)
+}
+
+// currentBucket returns index of bucket the line belongs to or -1
+func (h *histogram) currentBucket(l Line) int {
+	t, _ := timestamp.Parse(l.Str.Runes)
+	if t.IsZero() {
+		return -1
+	}
+
+	return h.index(t)
+}
+
+func (v *viewer) histogramKey() histogramKey {
+	return histogramKey{
+		width:          v.width,
+		lastOffset:     v.fetcher.lastWROffset(),
+		filters:        v.fetcher.filtersVersion,
+		filtersEnabled: v.fetcher.filtersEnabled,
+		search:         string(v.search),
+	}
+}
+
+// searchFunc returns function for current search or nil if there is no valid search
+func (v *viewer) searchFunc() filters.SearchFunc {
+	if len(v.search) == 0 {
+		return nil
+	}
+	searchFunc, err := filters.GetSearchFunc(v.info.searchType, v.search)
+	if err != nil {
+		return nil
+	}
+
+	return searchFunc
+}
+
+// refreshHistogram rebuilds histogram in background when lines, filters or search changed,
+// result is delivered to event loop by requestHistogram
+func (v *viewer) refreshHistogram() {
+	key := v.histogramKey()
+	if v.histogramBusy || key == v.histogramFor {
+		return
+	}
+	grown := key
+	grown.lastOffset = v.histogramFor.lastOffset
+	if grown == v.histogramFor && time.Since(v.histogramAt) < histogramInterval {
+		return // only new lines arrived, they are counted once per interval
+	}
+	v.histogramBusy = true
+	v.histogramFor = key
+	v.histogramAt = time.Now()
+
+	searchFunc := v.searchFunc()
+	go func() {
+		h := v.fetcher.histogram(v.ctx, &v.rates, key.width, key.search, searchFunc)
+		go termbox.Interrupt()
+		select {
+		case requestHistogram <- paneRequest[*histogram]{v, h}:
+		case <-v.ctx.Done():
+		}
+	}()
+}
+
+func (v *viewer) setHistogram(h *histogram) {
+	v.histogram = h
+	v.histogramBusy = false
+	v.draw()
+}
+
+func (v *viewer) toggleSparkline() {
+	v.sparkline = !v.sparkline
+	v.histogramFor = histogramKey{}
+	v.resize(v.width, v.rows)
+}
+
+// drawSparkline draws line rate as one row above infobar, buckets with matching lines are highlighted
+func (v *viewer) drawSparkline() {
+	y := v.height
+	for x := 0; x < v.width; x++ {
+		v.setCell(x, y, ' ', termbox.ColorDefault, termbox.ColorDefault)
+	}
+
+	h := v.histogram
+	if h == nil {
+		return
+	}
+
+	maxTotal, _ := h.max()
+	current := h.currentBucket(v.buffer.currentLine())
+	for x, b := range h.buckets {
+		if x >= v.width || b.total == 0 {
+			continue
+		}
+		fg := histogramTotalColor
+		if h.matching && b.matched > 0 {
+			fg = histogramMatchedColor
+		}
+		if x == current {
+			fg |= termbox.AttrReverse
+		}
+		v.setCell(x, y, sparks[b.total*(len(sparks)-1)/maxTotal], fg, termbox.ColorDefault)
+	}
+}
+
+func (v *viewer) showHistogram() {
+	hv := &histogramView{v: v, selected: -1}
+	v.focus = hv
+	v.histogramFor = histogramKey{}
+	v.refreshHistogram()
+	v.draw()
+}
+
+// histogramView is an overlay with line rate over time, selected bar can be jumped to
+type histogramView struct {
+	v        *viewer
+	selected int
+}
+
+func (hv *histogramView) draw() {
+	hv.v.clear()
+	defer func() { logging.LogOnErr(termbox.Flush()) }()
+
+	h := hv.v.histogram
+	if h == nil {
+		hv.v.printRow(0, "No lines with timestamps yet. q: close", termbox.ColorYellow, termbox.ColorDefault)
+		return
+	}
+
+	if hv.selected == -1 || hv.selected >= len(h.buckets) {
+		hv.selected = h.currentBucket(hv.v.buffer.currentLine())
+		if hv.selected == -1 {
+			hv.selected = len(h.buckets) - 1
+		}
+	}
+
+	b := h.buckets[hv.selected]
+	header := fmt.Sprintf("%s - %s  lines: %d", h.bucketTime(hv.selected).Format("2006-01-02 15:04:05"),
+		h.bucketTime(hv.selected+1).Format("15:04:05"), b.total)
+	if h.matching {
+		header += fmt.Sprintf("  matching: %d", b.matched)
+	}
+	hv.v.printRow(0, header+"  h/l: select Enter: jump q: close", termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+
+	// one row for header and one for time axis
+	rows := hv.v.height - 1
+	maxTotal, _ := h.max()
+	for x, b := range h.buckets {
+		if x >= hv.v.width || b.total == 0 {
+			continue
+		}
+		total := (b.total*rows*len(sparks) + maxTotal - 1) / maxTotal
+		matched := b.matched * rows * len(sparks) / maxTotal
+		for row := 0; row < rows; row++ {
+			level := total - row*len(sparks)
+			if level <= 0 {
+				break
+			}
+			fg := histogramTotalColor
+			if row*len(sparks) < matched {
+				fg = histogramMatchedColor
+			}
+			if x == hv.selected {
+				fg |= termbox.AttrBold
+			}
+			ch := sparks[len(sparks)-1]
+			if level < len(sparks) {
+				ch = sparks[level-1]
+			}
+			hv.v.setCell(x, rows-row, ch, fg, termbox.ColorDefault)
+		}
+	}
+	first := h.from.Format("15:04:05")
+	last := h.bucketTime(len(h.buckets)).Format("15:04:05")
+	hv.v.printRow(hv.v.height, "", termbox.ColorDefault, termbox.ColorDefault)
+	for i, ch := range first {
+		hv.v.setCell(i, hv.v.height, ch, termbox.ColorYellow, termbox.ColorDefault)
+	}
+	for i, ch := range last {
+		hv.v.setCell(hv.v.width-len(last)+i, hv.v.height, ch, termbox.ColorYellow, termbox.ColorDefault)
+	}
+	if hv.selected < hv.v.width {
+		hv.v.setCell(hv.selected, hv.v.height, '^', termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+	}
+}
+
+func (hv *histogramView) move(direction int) {
+	if hv.v.histogram == nil {
+		return
+	}
+	hv.selected += direction
+	if hv.selected >= len(hv.v.histogram.buckets) {
+		hv.selected = len(hv.v.histogram.buckets) - 1
+	}
+	if hv.selected < 0 {
+		hv.selected = 0
+	}
+	hv.draw()
+}
+
+// jump moves viewer to the first line of selected bucket, or of the next not empty one
+func (hv *histogramView) jump() action {
+	h := hv.v.histogram
+	if h == nil {
+		return ACTION_RESET_FOCUS
+	}
+	for i := hv.selected; i < len(h.buckets); i++ {
+		if h.buckets[i].offset != -1 {
+			hv.v.direction = DirectionUP
+			hv.v.following = false
+			hv.v.buffer.reset(Pos{POS_UNKNOWN, h.buckets[i].offset})
+			break
+		}
+	}
+
+	return ACTION_RESET_FOCUS
+}
+
+func (hv *histogramView) processKey(ev termbox.Event) action {
+	if ev.Ch != 0 {
+		switch ev.Ch {
+		case 'q':
+			return ACTION_RESET_FOCUS
+		case 'l':
+			hv.move(+1)
+		case 'h':
+			hv.move(-1)
+		case 'L':
+			hv.move(+10)
+		case 'H':
+			hv.move(-10)
+		case 'g':
+			hv.move(-hv.v.width)
+		case 'G':
+			hv.move(hv.v.width)
+		}
+		return NO_ACTION
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return ACTION_RESET_FOCUS
+	case termbox.KeyEnter:
+		return hv.jump()
+	case termbox.KeyArrowRight:
+		hv.move(+1)
+	case termbox.KeyArrowLeft:
+		hv.move(-1)
+	case termbox.KeyHome:
+		hv.move(-hv.v.width)
+	case termbox.KeyEnd:
+		hv.move(hv.v.width)
+	}
+
+	return NO_ACTION
+}
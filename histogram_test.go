@@ -0,0 +1,77 @@
+package dlog
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/level"
+	"github.com/dimcz/dlog/memfile"
+)
+
+func TestHistogram(t *testing.T) {
+	chunks := []string{
+		"2022-07-14T10:00:00Z start\n2022-07-14T10:00:01Z panic: boom\n",
+		"2022-07-14T10:00:01Z goroutine 1 [running]:\n2022-07-14T10:00:01Z \tmain.go:5\n",
+		"2022-07-14T10:00:05Z done\nno timestamp\n",
+		"2022-07-14T10:00:09Z last",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	file := memfile.New(nil)
+	f := NewFetcher(ctx, file)
+	var rc rateCounter
+	for i, chunk := range chunks {
+		if _, err := file.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+
+		got := f.histogram(ctx, &rc, 5, "", nil)
+		want := NewFetcher(ctx, memfile.New(file.Bytes())).histogram(ctx, new(rateCounter), 5, "", nil)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("test %d, histogram:\ngot  %+v\nwant %+v", i, got, want)
+		}
+	}
+
+	if _, err := file.Insert([]byte("2022-07-14T09:59:00Z history\n")); err != nil {
+		t.Fatal(err)
+	}
+	got := f.histogram(ctx, &rc, 5, "", nil)
+	want := &histogram{
+		buckets: []rateBucket{{total: 1, offset: 0}, {offset: -1}, {offset: -1}, {offset: -1}, {total: 5, offset: 29}},
+		from:    got.from,
+		step:    got.step,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inserted, histogram:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// TestHistogramFilters checks that lines are counted again when filter is replaced by another one
+func TestHistogramFilters(t *testing.T) {
+	data := "2022-07-14T10:00:00Z WARN slow\n" +
+		"2022-07-14T10:00:01Z ERROR failed\n" +
+		"2022-07-14T10:00:02Z WARN slow\n" +
+		"2022-07-14T10:00:03Z INFO done\n"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := NewFetcher(ctx, memfile.New([]byte(data)))
+	var rc rateCounter
+	for i, min := range []level.Level{level.Warn, level.Error, level.Info} {
+		f.filters = []*filters.Filter{filters.NewLevelFilter(min)}
+		f.filtersVersion++
+		f.filtersEnabled = true
+
+		fresh := NewFetcher(ctx, memfile.New([]byte(data)))
+		fresh.filters = []*filters.Filter{filters.NewLevelFilter(min)}
+		fresh.filtersEnabled = true
+		got := f.histogram(ctx, &rc, 4, "", nil)
+		want := fresh.histogram(ctx, new(rateCounter), 4, "", nil)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("test %d, level >= %s, histogram:\ngot  %+v\nwant %+v", i, min, got, want)
+		}
+	}
+}
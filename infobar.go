@@ -35,6 +35,8 @@ const (
 )
 
 type infoBar struct {
+	owner          *viewer // requests of infobar are delivered to it
+	x              int
 	y              int
 	width          int
 	cx             int // cursor position
@@ -49,6 +51,10 @@ type infoBar struct {
 	searchType     filters.SearchType
 	message        ibMessage
 	winName        string
+	meter          *meterReading
+	following      *bool
+	newLines       int  // lines arrived since following was paused
+	inactive       bool // another pane is focused
 }
 
 type ibMessage struct {
@@ -66,15 +72,15 @@ type ibHistory struct {
 	loaded       bool
 }
 
-var historyPath string
+var dlogDir, historyPath string
 
 func init() {
-	dir := os.Getenv("DLOG_DIR")
-	if len(dir) == 0 {
-		dir = filepath.Join(utils.GetHomeDir(), ".dlog")
+	dlogDir = os.Getenv("DLOG_DIR")
+	if len(dlogDir) == 0 {
+		dlogDir = filepath.Join(utils.GetHomeDir(), ".dlog")
 	}
 
-	historyPath = filepath.Join(dir, "history")
+	historyPath = filepath.Join(dlogDir, "history")
 }
 
 func (v *infoBar) moveCursor(direction int) error {
@@ -98,7 +104,7 @@ func (v *infoBar) reset(mode infoBarMode) {
 
 func (v *infoBar) clear() {
 	for i := 0; i < v.width; i++ {
-		termbox.SetCell(i, v.y, ' ', termbox.ColorDefault, termbox.ColorDefault)
+		v.setCell(i, v.y, ' ', termbox.ColorDefault, termbox.ColorDefault)
 	}
 }
 
@@ -110,24 +116,73 @@ func (v *infoBar) statusBar() {
 
 	str := []rune(fmt.Sprintf("%s/%d", *v.currentLine, v.totalLines))
 	for i := 0; i < len(str); i++ {
-		termbox.SetCell(v.width-len(str)+i, v.y, str[i], termbox.ColorYellow, termbox.ColorDefault)
+		v.setCell(v.width-len(str)+i, v.y, str[i], termbox.ColorYellow, termbox.ColorDefault)
+	}
+
+	end := v.drawFollowing(v.width - len(str) - 1)
+	if v.meter != nil {
+		v.drawMeter(end - 1)
 	}
 
 	name := []rune(v.winName)
+	nameColor := termbox.ColorYellow
+	if v.inactive {
+		nameColor = termbox.ColorDefault
+	}
 	for i := 0; i < len(name) && i+1 < v.width; i++ {
-		termbox.SetCell(i, v.y, name[i], termbox.ColorYellow, termbox.ColorDefault)
+		v.setCell(i, v.y, name[i], nameColor, termbox.ColorDefault)
 	}
 
 	if !*v.filtersEnabled {
 		str := []rune("[-FILTERS]")
 		for i := 0; i < len(str) && i+1 < v.width; i++ {
-			termbox.SetCell(i+1, v.y, str[i], termbox.ColorMagenta, termbox.ColorDefault)
+			v.setCell(i+1, v.y, str[i], termbox.ColorMagenta, termbox.ColorDefault)
 		}
 	}
 
 	logging.LogOnErr(termbox.Flush())
 }
 
+// drawFollowing draws whether new lines are followed ending at position end and returns its start
+func (v *infoBar) drawFollowing(end int) int {
+	str, color := "FOLLOW", termbox.ColorGreen
+	if !*v.following {
+		str, color = "PAUSED", termbox.ColorMagenta
+		if v.newLines > 0 {
+			str = fmt.Sprintf("PAUSED +%d new", v.newLines)
+		}
+	}
+
+	x := end - len(str)
+	if x <= len([]rune(v.winName))+1 {
+		return end
+	}
+	for i, ch := range str {
+		v.setCell(x+i, v.y, ch, color, termbox.ColorDefault)
+	}
+
+	return x
+}
+
+// drawMeter draws rates of incoming lines ending at position end, when there is room after the name
+func (v *infoBar) drawMeter(end int) {
+	rate := []rune(v.meter.String())
+	errors := []rune(v.meter.errorsString())
+	x := end - len(rate) - len(errors)
+	if x <= len([]rune(v.winName))+1 {
+		return
+	}
+
+	for _, ch := range rate {
+		v.setCell(x, v.y, ch, termbox.ColorCyan, termbox.ColorDefault)
+		x++
+	}
+	for _, ch := range errors {
+		v.setCell(x, v.y, ch, v.meter.errorsColor(), termbox.ColorDefault)
+		x++
+	}
+}
+
 func (v *infoBar) showSearch() {
 	v.moveCursorToPosition(v.cx)
 	v.syncSearchString()
@@ -136,28 +191,28 @@ func (v *infoBar) showSearch() {
 func (v *infoBar) draw() {
 	switch v.mode {
 	case ibModeBackSearch:
-		termbox.SetCell(0, v.y, '?', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '?', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeSearch:
-		termbox.SetCell(0, v.y, '/', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '/', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeFilter:
-		termbox.SetCell(0, v.y, '&', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '&', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeExclude:
-		termbox.SetCell(0, v.y, '-', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '-', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeHighlight:
-		termbox.SetCell(0, v.y, '~', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '~', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeSave:
-		termbox.SetCell(0, v.y, '>', termbox.ColorMagenta, termbox.ColorDefault)
+		v.setCell(0, v.y, '>', termbox.ColorMagenta, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeAppend:
-		termbox.SetCell(0, v.y, '+', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, '+', termbox.ColorGreen, termbox.ColorDefault)
 		v.showSearch()
 	case ibModeKeepCharacters:
-		termbox.SetCell(0, v.y, 'K', termbox.ColorGreen, termbox.ColorDefault)
+		v.setCell(0, v.y, 'K', termbox.ColorGreen, termbox.ColorDefault)
 		v.editBuffer = []rune(strconv.Itoa(*v.keepChars))
 		v.showSearch()
 		v.moveCursorToPosition(len(v.editBuffer))
@@ -188,7 +243,7 @@ func (v *infoBar) showMessage() {
 	str := []rune(v.message.str)
 	for i := 0; i < len(str) && i+1 < v.width; i++ {
 		logging.Debug("Adding char", str[i])
-		termbox.SetCell(i+1, v.y, str[i], v.message.color, termbox.ColorDefault)
+		v.setCell(i+1, v.y, str[i], v.message.color, termbox.ColorDefault)
 	}
 	logging.LogOnErr(termbox.Flush())
 }
@@ -239,7 +294,7 @@ func (v *infoBar) deleteWord(forward bool) {
 
 func (v *infoBar) moveCursorToPosition(pos int) {
 	v.cx = pos
-	termbox.SetCursor(pos+promptLength, v.y)
+	termbox.SetCursor(v.x+pos+promptLength, v.y)
 
 	logging.LogOnErr(termbox.Flush())
 }
@@ -253,15 +308,20 @@ func (v *infoBar) requestSearch() {
 	searchMode := v.mode
 	go func() {
 		go func() {
-			requestSearch <- infobarRequest{searchString, searchMode}
+			requestSearch <- infobarRequest{v.owner, searchString, searchMode}
 		}()
 		termbox.Interrupt()
 	}()
 }
 
-func (v *infoBar) resize(width, height int) {
+// resize places infobar at row y of the screen, starting from column x
+func (v *infoBar) resize(x, y, width int) {
+	v.x, v.y = x, y
 	v.width = width
-	v.y = height
+}
+
+func (v *infoBar) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(v.x+x, y, ch, fg, bg)
 }
 
 func (v *infoBar) processKey(ev termbox.Event) (a action) {
@@ -276,6 +336,8 @@ func (v *infoBar) processKey(ev termbox.Event) (a action) {
 
 		v.syncSearchString()
 
+	} else if b := lookup(inputKeymap, ev); b != nil {
+		return b.do(v, ev)
 	} else {
 		switch ev.Key {
 		case termbox.KeyEsc:
@@ -293,23 +355,10 @@ func (v *infoBar) processKey(ev termbox.Event) (a action) {
 				v.reset(ibModeStatus)
 				return ACTION_RESET_FOCUS
 			}
-		case termbox.KeyEnter:
-			v.addToHistory()
-			v.requestSearch()
-			v.reset(ibModeStatus)
-			return ACTION_RESET_FOCUS
 		case termbox.KeyArrowLeft:
 			logging.LogOnErr(v.moveCursor(-1))
 		case termbox.KeyArrowRight:
 			logging.LogOnErr(v.moveCursor(+1))
-		case termbox.KeyArrowUp:
-			v.onKeyUp()
-		case termbox.KeyArrowDown:
-			v.onKeyDown()
-		case termbox.KeyCtrlSlash:
-			v.switchSearchType()
-		case termbox.KeyCtrlR:
-			v.switchSearchType()
 		case termbox.KeyBackspace, termbox.KeyBackspace2:
 			err := v.moveCursor(-1)
 			if err == nil {
@@ -320,6 +369,15 @@ func (v *infoBar) processKey(ev termbox.Event) (a action) {
 	}
 	return
 }
+
+// apply adds input to history and requests its processing
+func (v *infoBar) apply(termbox.Event) action {
+	v.addToHistory()
+	v.requestSearch()
+	v.reset(ibModeStatus)
+	return ACTION_RESET_FOCUS
+}
+
 func (v *infoBar) switchSearchType() {
 	switch v.mode {
 	case ibModeExclude,
@@ -483,7 +541,7 @@ func (v *infoBar) navigateHistory(i int) {
 }
 
 func (v *infoBar) setPromptCell(x int, ch rune, fg, bg termbox.Attribute) {
-	termbox.SetCell(x+promptLength, v.y, ch, fg, bg)
+	v.setCell(x+promptLength, v.y, ch, fg, bg)
 }
 
 func (v *infoBar) syncSearchString() {
@@ -506,7 +564,7 @@ func (v *infoBar) syncSearchString() {
 	runeName := []rune(v.searchType.Name)
 	for i := v.width - len(runeName); i < v.width && i > promptLength; i++ {
 		c := i + len(runeName) - v.width
-		termbox.SetCell(i, v.y, runeName[c], v.searchType.Color, termbox.ColorDefault)
+		v.setCell(i, v.y, runeName[c], v.searchType.Color, termbox.ColorDefault)
 	}
 	logging.LogOnErr(termbox.Flush())
 }
@@ -514,6 +572,6 @@ func (v *infoBar) syncSearchString() {
 func (v *infoBar) changeKeepChars(direction int) {
 	go func() {
 		go termbox.Interrupt()
-		requestKeepCharsChange <- direction
+		requestKeepCharsChange <- paneRequest[int]{v.owner, direction}
 	}()
 }
@@ -0,0 +1,233 @@
+package dlog
+
+import (
+	"strings"
+
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/level"
+	"github.com/dimcz/dlog/logging"
+
+	"github.com/nsf/termbox-go"
+)
+
+// binding binds printable chars and special keys to action of T, help overlay is generated from bindings
+type binding[T any] struct {
+	section string
+	chars   []rune
+	keys    []termbox.Key
+	help    string
+	do      func(T, termbox.Event) action
+}
+
+var keyNames = map[termbox.Key]string{
+	termbox.KeyEsc:        "ESC",
+	termbox.KeyEnter:      "Enter",
+	termbox.KeyTab:        "Tab",
+	termbox.KeySpace:      "Space",
+	termbox.KeyArrowUp:    "Arrow up",
+	termbox.KeyArrowDown:  "Arrow down",
+	termbox.KeyArrowLeft:  "Arrow left",
+	termbox.KeyArrowRight: "Arrow right",
+	termbox.KeyPgup:       "PageUp",
+	termbox.KeyPgdn:       "PageDown",
+	termbox.KeyHome:       "Home",
+	termbox.KeyEnd:        "End",
+	termbox.KeyF1:         "F1",
+	termbox.KeyCtrlB:      "CTRL + B",
+	termbox.KeyCtrlD:      "CTRL + D",
+	termbox.KeyCtrlF:      "CTRL + F",
+	termbox.KeyCtrlH:      "CTRL + H",
+	termbox.KeyCtrlR:      "CTRL + R",
+	termbox.KeyCtrlU:      "CTRL + U",
+	termbox.KeyCtrlSlash:  "CTRL + /",
+}
+
+// names returns names of keys of binding as shown in help
+func (b *binding[T]) names() string {
+	names := make([]string, 0, len(b.chars)+len(b.keys))
+	for _, ch := range b.chars {
+		names = append(names, string(ch))
+	}
+	for _, key := range b.keys {
+		names = append(names, keyNames[key])
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// lookup returns binding of pressed key, nil if key is not bound
+func lookup[T any](bindings []binding[T], ev termbox.Event) *binding[T] {
+	for i := range bindings {
+		b := &bindings[i]
+		if ev.Ch != 0 && contains(b.chars, ev.Ch) || ev.Ch == 0 && contains(b.keys, ev.Key) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// call binds method of viewer without arguments
+func call(f func(v *viewer)) func(*viewer, termbox.Event) action {
+	return func(v *viewer, _ termbox.Event) action {
+		f(v)
+		return NO_ACTION
+	}
+}
+
+// input binds opening of infobar input in mode
+func input(mode infoBarMode) func(*viewer, termbox.Event) action {
+	return func(v *viewer, _ termbox.Event) action {
+		v.focus = &v.info
+		v.info.reset(mode)
+		return NO_ACTION
+	}
+}
+
+func quit(*viewer, termbox.Event) action {
+	logging.Debug("got key quit")
+	return ACTION_QUIT
+}
+
+// keymap binds keys of viewer, it is set in init as help overlay refers to it
+var keymap []binding[*viewer]
+
+func init() {
+	keymap = []binding[*viewer]{
+		{section: "Search/Filters", chars: []rune{'/'}, help: "Forward search", do: input(ibModeSearch)},
+		{section: "Search/Filters", chars: []rune{'?'}, help: "Back search", do: input(ibModeBackSearch)},
+		{section: "Search/Filters", chars: []rune{'n'}, help: "Next match",
+			do: call(func(v *viewer) { v.nextSearch(false) })},
+		{section: "Search/Filters", chars: []rune{'N'}, help: "Previous match",
+			do: call(func(v *viewer) { v.nextSearch(true) })},
+		{section: "Search/Filters", chars: []rune{filters.FilterIntersectChar}, help: "Filter: intersect",
+			do: input(ibModeFilter)},
+		{section: "Search/Filters", chars: []rune{filters.FilterExcludeChar}, help: "Filter: exclude",
+			do: input(ibModeExclude)},
+		{section: "Search/Filters", chars: []rune{filters.FilterUnionChar}, help: "Filter: union",
+			do: input(ibModeAppend)},
+		{section: "Search/Filters", chars: []rune{'='}, help: "Remove all filters, highlights are kept",
+			do: call((*viewer).dropFilters)},
+		{section: "Search/Filters", chars: []rune{'U'}, help: "Remove last filter", do: call((*viewer).removeLastFilter)},
+		{section: "Search/Filters", chars: []rune{'1', '2', '3', '4', '5', '6'},
+			help: "Filter: minimum log level, trace, debug, info, warn, error or fatal", do: setLevelFilter},
+		{section: "Search/Filters", chars: []rune{'0'}, help: "Remove log level filter", do: setLevelFilter},
+		{section: "Search/Filters", chars: []rune{'C'}, help: "Switch off/on all filters to see context of current line",
+			do: call((*viewer).switchFilters)},
+
+		{section: "Highlighting", chars: []rune{'`'}, help: "Mark current line, it is shown no matter what filters are",
+			do: call((*viewer).toggleCurrentHighlight)},
+		{section: "Highlighting", chars: []rune{filters.FilterHighlightChar}, help: "Highlight everything that matches",
+			do: input(ibModeHighlight)},
+		{section: "Highlighting", chars: []rune{'h'}, help: "Next highlighted line", do: call((*viewer).searchHighlighted)},
+		{section: "Highlighting", chars: []rune{'H'}, help: "Previous highlighted line",
+			do: call((*viewer).searchBackHighlighted)},
+		{section: "Highlighting", keys: []termbox.Key{termbox.KeyCtrlH}, help: "Remove all highlights",
+			do: call((*viewer).dropHighlights)},
+
+		{section: "Navigation", chars: []rune{'f'}, keys: []termbox.Key{termbox.KeyPgdn, termbox.KeySpace, termbox.KeyCtrlF},
+			help: "Page down", do: call((*viewer).navigatePageDown)},
+		{section: "Navigation", keys: []termbox.Key{termbox.KeyCtrlD}, help: "Half page down",
+			do: call((*viewer).navigateHalfPageDown)},
+		{section: "Navigation", chars: []rune{'b'}, keys: []termbox.Key{termbox.KeyPgup, termbox.KeyCtrlB},
+			help: "Page up", do: call((*viewer).navigatePageUp)},
+		{section: "Navigation", keys: []termbox.Key{termbox.KeyCtrlU}, help: "Half page up",
+			do: call((*viewer).navigateHalfPageUp)},
+		{section: "Navigation", chars: []rune{'g'}, keys: []termbox.Key{termbox.KeyHome}, help: "Go to first line",
+			do: call((*viewer).navigateStart)},
+		{section: "Navigation", chars: []rune{'G'}, keys: []termbox.Key{termbox.KeyEnd},
+			help: "Go to last line and follow new lines", do: call((*viewer).navigateEnd)},
+		{section: "Navigation", chars: []rune{'p'}, help: "Pause/Resume following of new lines",
+			do: call((*viewer).togglePause)},
+		{section: "Navigation", chars: []rune{'j'}, keys: []termbox.Key{termbox.KeyArrowDown}, help: "Move one line down",
+			do: call(func(v *viewer) { v.navigate(+1) })},
+		{section: "Navigation", chars: []rune{'k'}, keys: []termbox.Key{termbox.KeyArrowUp}, help: "Move one line up",
+			do: call(func(v *viewer) { v.navigate(-1) })},
+		{section: "Navigation", keys: []termbox.Key{termbox.KeyArrowRight}, help: "Next container, or scroll right",
+			do: call(func(v *viewer) { v.keyArrowRight() })},
+		{section: "Navigation", keys: []termbox.Key{termbox.KeyArrowLeft}, help: "Previous container, or scroll left",
+			do: call(func(v *viewer) { v.keyArrowLeft() })},
+		{section: "Navigation", chars: []rune{'c'}, help: "Select container from the list",
+			do: call((*viewer).showContainers)},
+		{section: "Navigation", chars: []rune{'>'}, help: "Scroll right by 1 column",
+			do: call(func(v *viewer) { v.navigateHorizontally(+1) })},
+		{section: "Navigation", chars: []rune{'<'}, help: "Scroll left by 1 column",
+			do: call(func(v *viewer) { v.navigateHorizontally(-1) })},
+		{section: "Navigation", chars: []rune{'m'}, help: "Bookmark current line, followed by bookmark letter",
+			do: startMark},
+		{section: "Navigation", chars: []rune{'\''}, help: "Jump to bookmark, followed by bookmark letter",
+			do: startMark},
+
+		{section: "Misc", chars: []rune{'K'}, help: "Keep N first columns when scrolling horizontally, arrows change N",
+			do: input(ibModeKeepCharacters)},
+		{section: "Misc", chars: []rune{'W'}, help: "Wrap/Unwrap lines", do: call((*viewer).toggleWrap)},
+		{section: "Misc", chars: []rune{'z'}, help: "Fold/Unfold multi-line entry on current line",
+			do: call((*viewer).toggleFolded)},
+		{section: "Misc", chars: []rune{'D'}, help: "Collapse duplicate lines: off, identical, similar",
+			do: call((*viewer).switchDedup)},
+		{section: "Misc", chars: []rune{'T'}, help: "Switch grouping of multi-line entries on/off",
+			do: call((*viewer).switchGrouping)},
+		{section: "Misc", chars: []rune{'J'}, help: "Expand/Collapse JSON payload of current line",
+			do: call((*viewer).toggleExpanded)},
+		{section: "Misc", chars: []rune{'S'}, help: "Summary of line patterns", do: call((*viewer).showSummary)},
+		{section: "Misc", keys: []termbox.Key{termbox.KeyEnter}, help: "Details of current line, copy it or its fields",
+			do: call((*viewer).showDetail)},
+		{section: "Misc", chars: []rune{'o'}, help: "Open target of hyperlink in current line",
+			do: call(func(v *viewer) { v.openLink(false) })},
+		{section: "Misc", chars: []rune{'O'}, help: "Copy target of hyperlink in current line to clipboard",
+			do: call(func(v *viewer) { v.openLink(true) })},
+		{section: "Misc", chars: []rune{'r'}, help: "Show/Hide sparkline of line rate", do: call((*viewer).toggleSparkline)},
+		{section: "Misc", chars: []rune{'R'}, help: "Histogram of line rate", do: call((*viewer).showHistogram)},
+		{section: "Misc", chars: []rune{'s'}, help: "Save lines passing filters to file", do: input(ibModeSave)},
+		{section: "Misc", chars: []rune{'M'}, help: "Write memory usage to debug log",
+			do: call(func(*viewer) { reportSystemUsage() })},
+		{section: "Misc", chars: []rune{'i'}, keys: []termbox.Key{termbox.KeyF1}, help: "Help, this list of keys",
+			do: call((*viewer).showHelp)},
+		{section: "Misc", chars: []rune{'q'}, keys: []termbox.Key{termbox.KeyEsc}, help: "Quit", do: quit},
+	}
+}
+
+func setLevelFilter(v *viewer, ev termbox.Event) action {
+	v.setLevelFilter(level.Level(ev.Ch - '0'))
+	return NO_ACTION
+}
+
+func startMark(v *viewer, ev termbox.Event) action {
+	v.startMark(ev.Ch)
+	return NO_ACTION
+}
+
+// screenKeymap binds keys of the screen, they are handled before keys of the focused pane
+var screenKeymap = []binding[*Dlog]{
+	{section: "Panes", chars: []rune{'|'}, help: "Split screen into panes side by side",
+		do: func(d *Dlog, _ termbox.Event) action { d.splitPane(splitVertical); return NO_ACTION }},
+	{section: "Panes", chars: []rune{'_'}, help: "Split screen into panes one above another",
+		do: func(d *Dlog, _ termbox.Event) action { d.splitPane(splitHorizontal); return NO_ACTION }},
+	{section: "Panes", keys: []termbox.Key{termbox.KeyTab}, help: "Move focus to the next pane",
+		do: func(d *Dlog, _ termbox.Event) action { d.focusPane((d.focused + 1) % len(d.panes)); return NO_ACTION }},
+	{section: "Panes", chars: []rune{'X'}, help: "Close focused pane",
+		do: func(d *Dlog, _ termbox.Event) action { d.closePane(); return NO_ACTION }},
+	{section: "Panes", chars: []rune{'L'}, help: "Sync scrolling of panes by timestamp on/off",
+		do: func(d *Dlog, _ termbox.Event) action { d.toggleSyncScroll(); return NO_ACTION }},
+}
+
+// inputKeymap binds special keys of infobar input, other keys edit the input
+var inputKeymap = []binding[*infoBar]{
+	{section: "Input", keys: []termbox.Key{termbox.KeyEnter}, help: "Apply input", do: (*infoBar).apply},
+	{section: "Input", keys: []termbox.Key{termbox.KeyArrowUp}, help: "Previous input from history, increase N in K mode",
+		do: func(v *infoBar, _ termbox.Event) action { v.onKeyUp(); return NO_ACTION }},
+	{section: "Input", keys: []termbox.Key{termbox.KeyArrowDown}, help: "Next input from history, decrease N in K mode",
+		do: func(v *infoBar, _ termbox.Event) action { v.onKeyDown(); return NO_ACTION }},
+	{section: "Input", keys: []termbox.Key{termbox.KeyCtrlSlash, termbox.KeyCtrlR}, help: "Switch search mode",
+		do: func(v *infoBar, _ termbox.Event) action { v.switchSearchType(); return NO_ACTION }},
+}
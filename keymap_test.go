@@ -0,0 +1,45 @@
+package dlog
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestKeymap(t *testing.T) {
+	chars := map[rune]string{}
+	keys := map[termbox.Key]string{}
+	check := func(section string, b []rune, k []termbox.Key, help string) {
+		if help == "" || len(b)+len(k) == 0 {
+			t.Errorf("binding %q of %s has no keys or help", help, section)
+		}
+		for _, ch := range b {
+			if other, ok := chars[ch]; ok {
+				t.Errorf("%q is bound to %q and %q", ch, other, help)
+			}
+			chars[ch] = help
+		}
+		for _, key := range k {
+			if _, ok := keyNames[key]; !ok {
+				t.Errorf("key %d of %q has no name", key, help)
+			}
+			if other, ok := keys[key]; ok {
+				t.Errorf("key %s is bound to %q and %q", keyNames[key], other, help)
+			}
+			keys[key] = help
+		}
+	}
+
+	// keys of the screen are handled before keys of the viewer, so they must not overlap
+	for _, b := range keymap {
+		check(b.section, b.chars, b.keys, b.help)
+	}
+	for _, b := range screenKeymap {
+		check(b.section, b.chars, b.keys, b.help)
+	}
+
+	keys = map[termbox.Key]string{}
+	for _, b := range inputKeymap {
+		check(b.section, b.chars, b.keys, b.help)
+	}
+}
@@ -0,0 +1,146 @@
+// Package level detects severity of log lines from common logging formats
+package level
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dimcz/dlog/fields"
+	"github.com/dimcz/dlog/timestamp"
+)
+
+type Level uint8
+
+const (
+	Unknown Level = iota
+	Trace
+	Debug
+	Info
+	Warn
+	Error
+	Fatal
+)
+
+var names = map[Level]string{
+	Unknown: "UNKNOWN",
+	Trace:   "TRACE",
+	Debug:   "DEBUG",
+	Info:    "INFO",
+	Warn:    "WARN",
+	Error:   "ERROR",
+	Fatal:   "FATAL",
+}
+
+func (l Level) String() string {
+	return names[l]
+}
+
+// Keys are names of fields commonly holding log level
+var Keys = []string{"level", "lvl", "severity", "loglevel"}
+
+// IsKey reports if field with given name holds log level
+func IsKey(key string) bool {
+	for _, k := range Keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	logfmtRe   = regexp.MustCompile(`(?:^|\s)(?:level|lvl|severity|loglevel)="?([A-Za-z]+)`)
+	bracketRe  = regexp.MustCompile(`(?i)\[(trace|debug|info|warn|warning|error|err|fatal|critical|crit|panic)]`)
+	glogRe     = regexp.MustCompile(`^([IWEF])\d{4} \d\d:\d\d:\d\d`)
+	pythonRe   = regexp.MustCompile(`^(DEBUG|INFO|WARNING|ERROR|CRITICAL):`)
+	keywordRe  = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|CRITICAL|PANIC)\b`)
+	glogLevels = map[string]Level{"I": Info, "W": Warn, "E": Error, "F": Fatal}
+)
+
+// Parse converts level name or numeric level of bunyan/pino (10..60) into Level
+func Parse(s string) Level {
+	if n, err := strconv.Atoi(s); err == nil {
+		switch {
+		case n >= 60:
+			return Fatal
+		case n >= 50:
+			return Error
+		case n >= 40:
+			return Warn
+		case n >= 30:
+			return Info
+		case n >= 20:
+			return Debug
+		case n >= 10:
+			return Trace
+		}
+		return Unknown
+	}
+
+	switch strings.ToLower(s) {
+	case "trace", "trc":
+		return Trace
+	case "debug", "dbg":
+		return Debug
+	case "info", "inf", "information", "notice":
+		return Info
+	case "warn", "warning", "wrn":
+		return Warn
+	case "error", "err", "eror":
+		return Error
+	case "fatal", "critical", "crit", "panic", "emerg", "alert", "dpanic":
+		return Fatal
+	}
+
+	return Unknown
+}
+
+// Detect returns level of the line: JSON level field, logfmt level=, [ERROR], glog E1017
+// and python WARNING: prefixes, uppercase level keyword. Docker timestamp is skipped
+func Detect(line []rune) Level {
+	_, n := timestamp.Parse(line)
+
+	return detect(string(line[n:]))
+}
+
+// DetectBytes is Detect of line encoded as UTF-8
+func DetectBytes(line []byte) Level {
+	_, n := timestamp.ParseBytes(line)
+
+	return detect(string(line[n:]))
+}
+
+func detect(payload string) Level {
+	if fields.IsJSON([]byte(payload)) {
+		if parsed, ok := fields.ParseJSON([]byte(payload)); ok {
+			for _, f := range parsed {
+				if IsKey(f.Key) {
+					return Parse(f.Value)
+				}
+			}
+		}
+		return Unknown
+	}
+
+	if m := logfmtRe.FindStringSubmatch(payload); m != nil {
+		if l := Parse(m[1]); l != Unknown {
+			return l
+		}
+	}
+	if m := glogRe.FindStringSubmatch(payload); m != nil {
+		return glogLevels[m[1]]
+	}
+	if m := pythonRe.FindStringSubmatch(payload); m != nil {
+		return Parse(m[1])
+	}
+	if m := bracketRe.FindStringSubmatch(payload); m != nil {
+		return Parse(m[1])
+	}
+	if m := keywordRe.FindStringSubmatch(payload); m != nil {
+		return Parse(m[1])
+	}
+
+	return Unknown
+}
@@ -0,0 +1,66 @@
+package level
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Level
+	}{
+		{"trace", Trace},
+		{"DBG", Debug},
+		{"Info", Info},
+		{"notice", Info},
+		{"WARNING", Warn},
+		{"eror", Error},
+		{"critical", Fatal},
+		{"panic", Fatal},
+		{"10", Trace},
+		{"20", Debug},
+		{"30", Info},
+		{"40", Warn},
+		{"50", Error},
+		{"60", Fatal},
+		{"5", Unknown},
+		{"verbose", Unknown},
+		{"", Unknown},
+	}
+
+	for i, tt := range tests {
+		if got := Parse(tt.s); got != tt.want {
+			t.Errorf("test %d, Parse(%q):\ngot  %v\nwant %v", i, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		line string
+		want Level
+	}{
+		{`{"level":"error","msg":"failed"}`, Error},
+		{`2022-07-14T10:15:42Z {"lvl":30,"msg":"started"}`, Info},
+		{`{"msg":"ERROR in message only"}`, Unknown},
+		{`time=10:15 level=warn msg="slow"`, Warn},
+		{`level="debug" msg=started`, Debug},
+		{`E1017 10:15:42.123456 1 main.go:5] failed`, Error},
+		{`WARNING:root:disk is almost full`, Warn},
+		{`2022-07-14 10:15:42 [error] connection refused`, Error},
+		{`2022-07-14 10:15:42 FATAL out of memory`, Fatal},
+		{`2022-07-14T10:15:42Z INFO started`, Info},
+		{`information about errors`, Unknown},
+		{`    at Main.main(Main.java:5)`, Unknown},
+		{``, Unknown},
+	}
+
+	for i, tt := range tests {
+		if got := DetectBytes([]byte(tt.line)); got != tt.want {
+			t.Errorf("test %d, DetectBytes(%q):\ngot  %v\nwant %v", i, tt.line, got, tt.want)
+		}
+		if got := Detect([]rune(tt.line)); got != tt.want {
+			t.Errorf("test %d, Detect(%q):\ngot  %v\nwant %v", i, tt.line, got, tt.want)
+		}
+	}
+}
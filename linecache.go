@@ -0,0 +1,107 @@
+package dlog
+
+import (
+	"hash/maphash"
+	"sync"
+
+	"github.com/dimcz/dlog/ansi"
+	"github.com/dimcz/dlog/level"
+)
+
+const lineCacheLimit = 4 << 20 // runes, about 70MB with attributes and text
+
+type cachedLine struct {
+	hash  uint64 // of raw bytes, lines at offset change when history is inserted or grouping is switched
+	str   ansi.Astring
+	text  []byte
+	level *lineLevel
+}
+
+// lineLevel is log level of decoded line, it is detected once, when it is used first time
+type lineLevel struct {
+	once  sync.Once
+	level level.Level
+}
+
+func (l *lineLevel) get(text []byte) level.Level {
+	l.once.Do(func() { l.level = level.DetectBytes(text) })
+
+	return l.level
+}
+
+// lineCache keeps lines decoded by ansi.NewAstring by offset, so redraws, searches in view buffer and
+// back-fills don't decode the same lines again. Lines are kept in two generations, when the current one
+// reaches half of the limit, the previous one is dropped, so recently used lines stay in cache
+type lineCache struct {
+	lock     sync.Mutex
+	seed     maphash.Seed
+	limit    int
+	size     int // runes in current generation
+	current  map[Offset]cachedLine
+	previous map[Offset]cachedLine
+}
+
+func newLineCache(limit int) *lineCache {
+	return &lineCache{
+		seed:     maphash.MakeSeed(),
+		limit:    limit,
+		current:  map[Offset]cachedLine{},
+		previous: map[Offset]cachedLine{},
+	}
+}
+
+// parse returns decoded line, its text and level, the cached ones when bytes at offset are the same.
+// Returned slices are shared, they must be copied before changing
+func (c *lineCache) parse(l PosLine) (ansi.Astring, []byte, *lineLevel) {
+	if c == nil {
+		str, text := decode(l.b)
+		return str, text, new(lineLevel)
+	}
+
+	hash := maphash.Bytes(c.seed, l.b)
+	c.lock.Lock()
+	cached, ok := c.current[l.Offset]
+	if !ok {
+		if cached, ok = c.previous[l.Offset]; ok && cached.hash == hash {
+			c.add(l.Offset, cached)
+		}
+	}
+	c.lock.Unlock()
+	if ok && cached.hash == hash {
+		return cached.str, cached.text, cached.level
+	}
+
+	str, text := decode(l.b)
+	// capacity is cut, so appending to the line copies it instead of writing into shared array
+	str.Runes = str.Runes[:len(str.Runes):len(str.Runes)]
+	str.Attrs = str.Attrs[:len(str.Attrs):len(str.Attrs)]
+
+	lvl := new(lineLevel)
+	c.lock.Lock()
+	c.add(l.Offset, cachedLine{hash: hash, str: str, text: text, level: lvl})
+	c.lock.Unlock()
+
+	return str, text, lvl
+}
+
+// decode returns line decoded by ansi.NewAstring and its text, which is raw line itself
+// when there is nothing to decode
+func decode(b []byte) (ansi.Astring, []byte) {
+	str := ansi.NewAstring(b)
+	if ansi.IsPlain(b) {
+		return str, b[:len(b):len(b)]
+	}
+
+	return str, []byte(string(str.Runes))
+}
+
+func (c *lineCache) add(offset Offset, l cachedLine) {
+	if old, ok := c.current[offset]; ok {
+		c.size -= len(old.str.Runes) + 1
+	}
+	c.current[offset] = l
+	c.size += len(l.str.Runes) + 1
+	if c.size > c.limit/2 {
+		c.previous, c.current, c.size = c.current, map[Offset]cachedLine{}, 0
+	}
+}
@@ -0,0 +1,62 @@
+package dlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dimcz/dlog/memfile"
+)
+
+func TestLineCache(t *testing.T) {
+	c := newLineCache(lineCacheLimit)
+	tests := []struct {
+		offset Offset
+		b      string
+		want   string
+	}{
+		{0, "\x1b[31mfirst\x1b[0m", "first"},
+		{0, "\x1b[31mfirst\x1b[0m", "first"},
+		{0, "second", "second"},
+		{6, "\x1b[32mthird\x1b[0m", "third"},
+		{0, "\x1b[31mfirst\x1b[0m", "first"},
+	}
+
+	for i, tt := range tests {
+		str, text, _ := c.parse(PosLine{[]byte(tt.b), Pos{0, tt.offset}})
+		if string(str.Runes) != tt.want || string(text) != tt.want {
+			t.Errorf("test %d, parse(%d, %q):\ngot  %q, %q\nwant %q", i, tt.offset, tt.b, string(str.Runes), text, tt.want)
+		}
+	}
+}
+
+// TestLineCacheChanged checks that line cached at offset is not returned, when other line starts there
+func TestLineCacheChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := func(f *Fetcher) string {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for l := range f.Get(ctx, Pos{0, 0}) {
+			return string(l.Text)
+		}
+		return ""
+	}
+
+	file := memfile.New([]byte("\x1b[32mnew\x1b[0m\n"))
+	f := NewFetcher(ctx, file)
+	if got := first(f); got != "new" {
+		t.Errorf("before insert:\ngot  %q\nwant %q", got, "new")
+	}
+	if _, err := file.Insert([]byte("\x1b[31mold\x1b[0m\n\tat Main.main(Main.java:5)\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "old\n\tat Main.main(Main.java:5)"
+	if got := first(f); got != want {
+		t.Errorf("after insert:\ngot  %q\nwant %q", got, want)
+	}
+
+	f.switchGrouping()
+	if got := first(f); got != "old" {
+		t.Errorf("after grouping switch:\ngot  %q\nwant %q", got, "old")
+	}
+}
@@ -0,0 +1,52 @@
+package logfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/dimcz/dlog/logging"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompressor returns reader decompressing f detected by magic bytes, nil if f is not compressed
+func Decompressor(f *os.File) (io.ReadCloser, error) {
+	magic := make([]byte, len(zstdMagic))
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(f)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+
+	return nil, nil
+}
+
+// decompress streams compressed file into memfile. Archives are not expected to grow,
+// so file is read only once, viewer may navigate and search while it is in progress
+func (l *LogFile) decompress(r io.ReadCloser) {
+	defer logging.Timeit("decompress")()
+
+	defer func() {
+		logging.LogOnErr(r.Close())
+	}()
+
+	l.copy(r, make([]byte, chunkSize))
+}
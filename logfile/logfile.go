@@ -0,0 +1,220 @@
+package logfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/memfile"
+	"github.com/dimcz/dlog/utils"
+)
+
+const (
+	chunkSize    = 64 * 1024
+	pollInterval = 250 * time.Millisecond
+)
+
+// LogFile follows files on disk the way `tail -F` does: appended data is fed into memfile,
+// truncation and replacement of the file by logrotate are detected and reading continues from the new file.
+// Gzip and zstd compressed files are decompressed into memfile without following
+type LogFile struct {
+	file    *memfile.File
+	paths   []string
+	current int
+
+	wg            *sync.WaitGroup
+	parentContext context.Context
+	ctx           context.Context
+	cancel        func()
+}
+
+func Open(ctx context.Context, file *memfile.File, paths []string) (*LogFile, error) {
+	for _, path := range paths {
+		if err := utils.ValidateRegularFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LogFile{
+		parentContext: ctx,
+		file:          file,
+		paths:         paths,
+		wg:            new(sync.WaitGroup),
+	}, nil
+}
+
+func (l *LogFile) Follow() int64 {
+	l.ctx, l.cancel = context.WithCancel(l.parentContext)
+
+	l.file.Clear()
+
+	logging.Debug("execute following process", l.paths[l.current])
+	l.wg.Add(1)
+	go l.tail(l.paths[l.current])
+
+	return -1
+}
+
+// Append does nothing, whole file is read by Follow
+func (l *LogFile) Append(int64, func()) {}
+
+func (l *LogFile) Name() string {
+	return fmt.Sprintf("(%d/%d) %s", l.current+1, len(l.paths), l.paths[l.current])
+}
+
+// Split returns source following the same files into file, starting from the current one
+func (l *LogFile) Split(ctx context.Context, file *memfile.File) *LogFile {
+	return &LogFile{
+		parentContext: ctx,
+		file:          file,
+		paths:         l.paths,
+		current:       l.current,
+		wg:            new(sync.WaitGroup),
+	}
+}
+
+// Stop cancels following and waits for it to finish
+func (l *LogFile) Stop() {
+	l.cancel()
+	l.wg.Wait()
+}
+
+func (l *LogFile) LogName() string {
+	if path, err := filepath.Abs(l.paths[l.current]); err == nil {
+		return path
+	}
+
+	return l.paths[l.current]
+}
+
+// Containers returns paths of files and index of the current one
+func (l *LogFile) Containers() ([]string, int) {
+	return l.paths, l.current
+}
+
+func (l *LogFile) SelectContainer(i int) {
+	l.Stop()
+
+	l.current = i
+}
+
+func (l *LogFile) NextContainer() {
+	l.Stop()
+
+	c := l.current + 1
+	if c >= len(l.paths) {
+		c = 0
+	}
+	l.current = c
+}
+
+func (l *LogFile) PrevContainer() {
+	l.Stop()
+
+	c := l.current - 1
+	if c < 0 {
+		c = len(l.paths) - 1
+	}
+	l.current = c
+}
+
+func (l *LogFile) tail(path string) {
+	defer l.wg.Done()
+
+	f, err := os.Open(path)
+	if err != nil {
+		logging.Debug("failed to open file:", err)
+		return
+	}
+
+	defer func() {
+		logging.LogOnErr(f.Close())
+	}()
+
+	r, err := Decompressor(f)
+	if err != nil {
+		logging.Debug("failed to read compressed file:", err)
+		return
+	}
+	if r != nil {
+		l.decompress(r)
+		return
+	}
+
+	var offset int64
+	buf := make([]byte, chunkSize)
+	for live := false; ; live = true {
+		offset += l.copy(f, buf)
+		if !live {
+			l.file.Live() // content of the file is history, lines appended afterwards arrive live
+		}
+
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue // rotated away, new file is not created yet
+		}
+
+		current, err := f.Stat()
+		if err != nil {
+			logging.Debug(err)
+			return
+		}
+
+		switch {
+		case !os.SameFile(fi, current):
+			logging.Debug("file replaced", path)
+			offset += l.copy(f, buf) // leftovers written before rotation
+
+			nf, err := os.Open(path)
+			if err != nil {
+				logging.Debug(err)
+				continue
+			}
+			logging.LogOnErr(f.Close())
+			f, offset = nf, 0
+		case fi.Size() < offset:
+			logging.Debug("file truncated", path)
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				logging.Debug(err)
+				return
+			}
+			offset = 0
+		}
+	}
+}
+
+// copy writes everything available in r to memfile and returns number of copied bytes
+func (l *LogFile) copy(r io.Reader, buf []byte) int64 {
+	var n int64
+	for {
+		select {
+		case <-l.ctx.Done():
+			return n
+		default:
+		}
+
+		c, err := r.Read(buf)
+		if c > 0 {
+			_, werr := l.file.Write(buf[:c])
+			logging.LogOnErr(werr)
+			n += int64(c)
+		}
+		if err != nil {
+			if err != io.EOF {
+				logging.Debug(err)
+			}
+			return n
+		}
+	}
+}
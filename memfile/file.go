@@ -23,6 +23,12 @@ type File struct {
 	b        []byte
 	pos      int
 	writePos int
+	written  int64   // bytes appended by Write in total
+	tail     int     // bytes appended by Write since Clear, they are at the end of file
+	live     int64   // total of written bytes when live data started, -1 while history is written after Clear
+	version  int64   // changed by Insert and Clear, which move data at known offsets
+	cleared  int64   // version set by the last Clear
+	inserted []int64 // total of bytes inserted in front since Clear, after each Insert
 }
 
 // New creates and initializes a new File using b as its initial contents.
@@ -71,6 +77,8 @@ func (fb *File) Insert(b []byte) (int, error) {
 
 	fb.b = append(b, fb.b...)
 	fb.pos += len(b)
+	fb.version++
+	fb.inserted = append(fb.inserted, fb.insertedAt(fb.version-1)+int64(len(b)))
 
 	if fb.writePos == 0 {
 		fb.writePos = fb.pos
@@ -89,6 +97,8 @@ func (fb *File) Write(b []byte) (int, error) {
 
 	n, err := fb.writeAt(b, int64(len(fb.b)))
 	fb.writePos += n
+	fb.written += int64(n)
+	fb.tail += n
 
 	return n, err
 }
@@ -164,10 +174,53 @@ func (fb *File) Clear() {
 	fb.m.Lock()
 	defer fb.m.Unlock()
 
-	fb.pos, fb.writePos = 0, 0
+	fb.pos, fb.writePos, fb.tail = 0, 0, 0
+	fb.live = -1
+	fb.version++
+	fb.cleared, fb.inserted = fb.version, nil
 	fb.b = nil
 }
 
+// Version changes when data is inserted in front or cleared, so offsets of data read before
+// are not valid anymore. Data appended by Write keeps version
+func (fb *File) Version() int64 {
+	fb.m.Lock()
+	defer fb.m.Unlock()
+
+	return fb.version
+}
+
+// Moved returns how far data at offsets known at version moved since then, as data was inserted in front of it.
+// ok is false when file was cleared since version, so the offsets are not valid anymore
+func (fb *File) Moved(version int64) (n int64, ok bool) {
+	fb.m.Lock()
+	defer fb.m.Unlock()
+
+	if version < fb.cleared || version > fb.version {
+		return 0, false
+	}
+
+	return fb.insertedAt(fb.version) - fb.insertedAt(version), true
+}
+
+// insertedAt returns total of bytes inserted in front since Clear till version
+func (fb *File) insertedAt(version int64) int64 {
+	if version <= fb.cleared {
+		return 0
+	}
+
+	return fb.inserted[version-fb.cleared-1]
+}
+
+// Live marks that data written from now on arrives live, data written before it since Clear is history.
+// Until Clear is called all written data is live
+func (fb *File) Live() {
+	fb.m.Lock()
+	defer fb.m.Unlock()
+
+	fb.live = fb.written
+}
+
 // Bytes returns the full contents of the File.
 // The result in only valid until the next Write, WriteAt, or Truncate call.
 func (fb *File) Bytes() []byte {
@@ -180,6 +233,31 @@ func (fb *File) WriteOffset() int {
 	return fb.writePos
 }
 
+// Written returns live data appended by Write since total of written bytes was equal to from,
+// its offset in the file and current total. Data written before Clear and history are not returned.
+// The result in only valid until the next Write, WriteAt, or Truncate call.
+func (fb *File) Written(from int64) ([]byte, int64, int64) {
+	fb.m.Lock()
+	defer fb.m.Unlock()
+
+	if fb.live < 0 {
+		return nil, int64(len(fb.b)), fb.written
+	}
+	if from < fb.live {
+		from = fb.live
+	}
+	n := fb.written - from
+	if n > int64(fb.tail) {
+		n = int64(fb.tail)
+	}
+	start := int64(len(fb.b)) - n
+	if n <= 0 {
+		return nil, start, fb.written
+	}
+
+	return fb.b[start:], start, fb.written
+}
+
 // A fileStat is the implementation of FileInfo returned by Stat.
 type fileStat struct {
 	name    string
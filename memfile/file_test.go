@@ -199,3 +199,64 @@ func readFull(r io.Reader, b []byte) (n int, err error) {
 	}
 	return len(b0) - len(b), err
 }
+
+func TestWritten(t *testing.T) {
+	var fb File
+	mustWrite := func(s string) {
+		if _, err := fb.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		step func()
+		from int64
+		want string
+	}{
+		{func() { mustWrite("a\n") }, 0, "a\n"},
+		{func() { fb.Clear(); mustWrite("history\n") }, 0, ""},
+		{func() { fb.Live(); mustWrite("live\n") }, 0, "live\n"},
+		{func() { mustWrite("more\n") }, 15, "more\n"},
+		{func() { fb.Clear(); mustWrite("next\n") }, 0, ""},
+	}
+
+	for i, tt := range tests {
+		tt.step()
+		if got, _, _ := fb.Written(tt.from); string(got) != tt.want {
+			t.Errorf("test %d, Written(%d):\ngot  %q\nwant %q", i, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestMoved(t *testing.T) {
+	var fb File
+	mustInsert := func(s string) {
+		if _, err := fb.Insert([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		step    func()
+		version int64 // offsets are known at
+		want    int64
+		wantOk  bool
+	}{
+		{func() {}, 0, 0, true},
+		{func() { mustInsert("abc") }, 0, 3, true},
+		{func() { mustInsert("de") }, 0, 5, true},
+		{func() {}, 1, 2, true},
+		{func() {}, 2, 0, true},
+		{func() { _, _ = fb.Write([]byte("f")) }, 1, 2, true},
+		{func() { fb.Clear() }, 2, 0, false},
+		{func() { mustInsert("g") }, 3, 1, true},
+		{func() {}, 5, 0, false},
+	}
+
+	for i, tt := range tests {
+		tt.step()
+		if got, ok := fb.Moved(tt.version); got != tt.want || ok != tt.wantOk {
+			t.Errorf("test %d, Moved(%d):\ngot  %d, %v\nwant %d, %v", i, tt.version, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package dlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/dimcz/dlog/config"
+	"github.com/dimcz/dlog/memfile"
+
+	"code.cloudfoundry.org/bytefmt"
+	"github.com/nsf/termbox-go"
+)
+
+const (
+	meterInterval = time.Second
+	meterWindow   = 8 // samples kept for trend
+)
+
+type rates struct {
+	lines  float64
+	bytes  float64
+	errors float64
+}
+
+// meter measures rate of data written into memfile, i.e. arriving while following
+type meter struct {
+	file         *memfile.File
+	errorPattern *regexp.Regexp
+	written      int64
+	at           time.Time
+	history      []rates // the latest sample is the last one
+}
+
+// meterReading is shown in status bar
+type meterReading struct {
+	current    rates
+	trend      string // sparkline of lines rate
+	errorTrend rune
+}
+
+func newMeter(file *memfile.File) *meter {
+	_, _, written := file.Written(0)
+
+	return &meter{
+		file:         file,
+		errorPattern: config.GetValue().Errors,
+		written:      written,
+		at:           time.Now(),
+	}
+}
+
+func (m *meter) sample(now time.Time) {
+	data, _, written := m.file.Written(m.written)
+	m.written = written
+	seconds := now.Sub(m.at).Seconds()
+	m.at = now
+
+	var r rates
+	if seconds > 0 {
+		lines := bytes.Count(data, []byte{'\n'})
+		errors := 0
+		for _, line := range bytes.Split(data, []byte{'\n'}) {
+			if m.errorPattern.Match(line) {
+				errors++
+			}
+		}
+
+		r = rates{
+			lines:  float64(lines) / seconds,
+			bytes:  float64(len(data)) / seconds,
+			errors: float64(errors) / seconds,
+		}
+	}
+
+	m.history = append(m.history, r)
+	if len(m.history) > meterWindow {
+		m.history = m.history[1:]
+	}
+}
+
+// reading returns nil when nothing was written during the window
+func (m *meter) reading() *meterReading {
+	var maxLines float64
+	for _, r := range m.history {
+		if r.lines > maxLines {
+			maxLines = r.lines
+		}
+	}
+	if maxLines == 0 {
+		return nil
+	}
+
+	trend := make([]rune, len(m.history))
+	for i, r := range m.history {
+		trend[i] = sparks[int(r.lines*float64(len(sparks)-1)/maxLines)]
+	}
+
+	return &meterReading{
+		current:    m.history[len(m.history)-1],
+		trend:      string(trend),
+		errorTrend: m.errorTrend(),
+	}
+}
+
+// errorTrend compares the latest error rate with average over the window
+func (m *meter) errorTrend() rune {
+	last := m.history[len(m.history)-1].errors
+	var avg float64
+	for _, r := range m.history[:len(m.history)-1] {
+		avg += r.errors
+	}
+	if len(m.history) > 1 {
+		avg /= float64(len(m.history) - 1)
+	}
+
+	switch {
+	case last > avg*1.5 && last > 0:
+		return '↑'
+	case last < avg*0.5:
+		return '↓'
+	default:
+		return '→'
+	}
+}
+
+func (r *meterReading) String() string {
+	return fmt.Sprintf("%s %.0f l/s %s/s", r.trend, r.current.lines, bytefmt.ByteSize(uint64(r.current.bytes)))
+}
+
+func (r *meterReading) errorsString() string {
+	return fmt.Sprintf(" err %.1f/s%c", r.current.errors, r.errorTrend)
+}
+
+func (r *meterReading) errorsColor() termbox.Attribute {
+	if r.current.errors > 0 {
+		return termbox.ColorRed
+	}
+
+	return termbox.ColorCyan
+}
+
+// measure samples write activity each second and delivers reading to event loop
+func (v *viewer) measure(ctx context.Context) {
+	m := newMeter(v.fetcher.reader)
+	var prev *meterReading
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-time.After(meterInterval):
+			m.sample(now)
+			reading := m.reading()
+			if reading == nil && prev == nil {
+				continue // idle, status bar is not redrawn
+			}
+			prev = reading
+			go termbox.Interrupt()
+			select {
+			case requestMeterUpdate <- paneRequest[*meterReading]{v, reading}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package dlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dimcz/dlog/utils"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+const wheelLines = 3
+
+// mouseHandler is implemented by views handling mouse, x and y are relative to the pane
+type mouseHandler interface {
+	processMouse(ev termbox.Event, x, y int) action
+}
+
+// selection is text selected by dragging mouse, from the cell button was pressed on to the current one
+type selection struct {
+	x1, y1  int
+	x2, y2  int
+	dragged bool
+}
+
+// ordered returns ends of selection in reading order
+func (s *selection) ordered() (x1, y1, x2, y2 int) {
+	if s.y1 < s.y2 || s.y1 == s.y2 && s.x1 <= s.x2 {
+		return s.x1, s.y1, s.x2, s.y2
+	}
+
+	return s.x2, s.y2, s.x1, s.y1
+}
+
+// columns returns range of columns selected on row y of the pane
+func (s *selection) columns(y, width int) (from, to int) {
+	x1, y1, x2, y2 := s.ordered()
+	from, to = 0, width
+	if y == y1 {
+		from = x1
+	}
+	if y == y2 {
+		to = x2 + 1
+	}
+
+	return from, to
+}
+
+func (v *viewer) processMouse(ev termbox.Event, x, y int) action {
+	switch {
+	case ev.Key == termbox.MouseWheelUp:
+		v.navigate(-wheelLines)
+	case ev.Key == termbox.MouseWheelDown:
+		v.navigate(+wheelLines)
+	case ev.Key == termbox.MouseLeft && ev.Mod&termbox.ModMotion != 0:
+		if v.selection == nil {
+			break
+		}
+		v.selection.x2, v.selection.y2 = clamp(x, 0, v.width-1), clamp(y, 0, v.height-1)
+		v.selection.dragged = true
+		v.draw()
+	case ev.Key == termbox.MouseLeft:
+		v.selection = &selection{x1: x, y1: y, x2: x, y2: y}
+	case ev.Key == termbox.MouseRelease:
+		s := v.selection
+		v.selection = nil
+		switch {
+		case s == nil:
+		case s.dragged:
+			v.copySelection(s)
+		default:
+			v.click(s.x1, s.y1)
+		}
+	}
+
+	return NO_ACTION
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+
+	return n
+}
+
+// click selects clicked line without scrolling, click on the selected line drops selection.
+// Click on container name in status bar opens container selection
+func (v *viewer) click(x, y int) {
+	if y == v.info.y-v.y {
+		if v.info.mode == ibModeStatus && x < len([]rune(v.info.winName)) {
+			v.showContainers()
+		}
+		return
+	}
+
+	if y >= v.height || y >= len(v.rowLines) {
+		return
+	}
+	line, err := v.buffer.getLine(v.rowLines[y])
+	if err != nil {
+		return
+	}
+	v.rebaseMarks()
+	if line.Offset == v.selected {
+		v.selected = -1
+	} else {
+		v.selected = line.Offset
+	}
+	v.draw()
+}
+
+// currentIndex returns index of current line relative to the top line. It is the line selected by click
+// while it is shown on the screen, otherwise the top line
+func (v *viewer) currentIndex() int {
+	v.rebaseMarks()
+	if v.selected < 0 {
+		return 0
+	}
+	for _, i := range v.rowLines {
+		if l, err := v.buffer.getLine(i); err == nil && l.Offset == v.selected {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// currentLine returns line actions like marking, bookmarking, folding and details act on
+func (v *viewer) currentLine() Line {
+	line, _ := v.buffer.getLine(v.currentIndex())
+
+	return line
+}
+
+// drawSelection reverses selected cells drawn on the screen
+func (v *viewer) drawSelection() {
+	_, y1, _, y2 := v.selection.ordered()
+	screenWidth, _ := termbox.Size()
+	cells := termbox.CellBuffer()
+	for y := y1; y <= y2; y++ {
+		from, to := v.selection.columns(y, v.width)
+		for x := from; x < to; x++ {
+			c := cells[(v.y+y)*screenWidth+v.x+x]
+			v.setCell(x, y, c.Ch, c.Fg^termbox.AttrReverse, c.Bg)
+		}
+	}
+}
+
+// copySelection copies text drawn in selection to clipboard, rows of wrapped line are joined
+func (v *viewer) copySelection(s *selection) {
+	_, y1, _, y2 := s.ordered()
+	screenWidth, _ := termbox.Size()
+	cells := termbox.CellBuffer()
+
+	var text strings.Builder
+	for y := y1; y <= y2; y++ {
+		if y > y1 && (y >= len(v.rowLines) || v.rowLines[y] != v.rowLines[y-1]) {
+			text.WriteByte('\n')
+		}
+
+		var row []rune
+		from, to := s.columns(y, v.width)
+		for x := from; x < to; x++ {
+			ch := cells[(v.y+y)*screenWidth+v.x+x].Ch
+			row = append(row, ch)
+			if runewidth.RuneWidth(ch) == 2 {
+				x++ // cell after wide character is covered by it
+			}
+		}
+		text.WriteString(strings.TrimRight(string(row), " "))
+	}
+
+	v.draw() // selection is not drawn anymore
+	if err := utils.CopyToClipboard(text.String()); err != nil {
+		v.info.setMessage(ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed})
+		return
+	}
+	v.info.setMessage(ibMessage{
+		str:   fmt.Sprintf("Copied %d characters to clipboard", len([]rune(text.String()))),
+		color: termbox.ColorGreen,
+	})
+}
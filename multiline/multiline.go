@@ -0,0 +1,54 @@
+// Package multiline decides which lines continue previous log entry, e.g. stack traces
+// of Java, Python or Go panics, so they can be treated as one multi-line entry
+package multiline
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/dimcz/dlog/timestamp"
+)
+
+var (
+	goroutineRe = regexp.MustCompile(`^goroutine \d+ \[`)
+	goFuncRe    = regexp.MustCompile(`^(created by [\w./*()\[\]-]+|[\w./*()\[\]-]+\(.*\))( in goroutine \d+)?$`)
+	pyErrorRe   = regexp.MustCompile(`^[\w.]+(Error|Exception|Warning|Exit|Interrupt)(: .*)?$`)
+
+	continuationPrefixes = [][]byte{
+		[]byte("at "),
+		[]byte("Caused by:"),
+		[]byte("Suppressed:"),
+		[]byte("Traceback (most recent call last):"),
+		[]byte("During handling of the above exception"),
+		[]byte("The above exception was the direct cause"),
+	}
+)
+
+// Continues reports if next line belongs to the same entry as prev, the last line of the entry.
+// Continuation lines are indented, empty, start with `at `, `Caused by:`, `goroutine N [`,
+// or are function and exception lines inside of Go and Python traces
+func Continues(prev, next []byte) bool {
+	prev, next = timestamp.Strip(prev), timestamp.Strip(next)
+
+	if len(bytes.TrimSpace(next)) == 0 {
+		return true
+	}
+	if next[0] == ' ' || next[0] == '\t' {
+		return true
+	}
+	for _, prefix := range continuationPrefixes {
+		if bytes.HasPrefix(next, prefix) {
+			return true
+		}
+	}
+	if goroutineRe.Match(next) {
+		return true
+	}
+
+	inTrace := len(prev) > 0 && (prev[0] == ' ' || prev[0] == '\t' || goroutineRe.Match(prev))
+	if inTrace && (goFuncRe.Match(next) || pyErrorRe.Match(next)) {
+		return true
+	}
+
+	return false
+}
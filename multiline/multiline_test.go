@@ -0,0 +1,38 @@
+package multiline
+
+import (
+	"testing"
+)
+
+func TestContinues(t *testing.T) {
+	tests := []struct {
+		prev, next string
+		want       bool
+	}{
+		{"ERROR failed", "ERROR again", false},
+		{"ERROR failed", "", true},
+		{"ERROR failed", "   ", true},
+		{"Exception in thread \"main\" java.lang.NullPointerException", "\tat Main.main(Main.java:5)", true},
+		{"\tat Main.main(Main.java:5)", "Caused by: java.io.IOException: closed", true},
+		{"ERROR failed", "at the end of the day", true},
+		{"Traceback (most recent call last):", "  File \"app.py\", line 3, in <module>", true},
+		{"    raise ValueError(\"boom\")", "ValueError: boom", true},
+		{"INFO started", "ValueError: boom", false},
+		{"panic: boom", "goroutine 1 [running]:", true},
+		{"INFO started", "goroutine 7 [chan receive]:", true},
+		{"goroutine 1 [running]:", "main.main()", true},
+		{"\t/app/main.go:5 +0x1d", "created by main.start in goroutine 1", true},
+		{"\t/app/main.go:5 +0x1d", "created by main.start", true},
+		{"INFO started", "main.main()", false},
+		{"2022-07-14T10:00:00Z panic: boom", "2022-07-14T10:00:00Z goroutine 1 [running]:", true},
+		{"2022-07-14T10:00:00Z \tmain.go:5", "2022-07-14T10:00:01Z main.main()", true},
+		{"2022-07-14T10:00:00Z INFO started", "2022-07-14T10:00:01Z INFO done", false},
+		{"2022-07-14T10:00:00Z INFO started", "2022-07-14T10:00:01Z ", true},
+	}
+
+	for i, tt := range tests {
+		if got := Continues([]byte(tt.prev), []byte(tt.next)); got != tt.want {
+			t.Errorf("test %d, Continues(%q, %q):\ngot  %v\nwant %v", i, tt.prev, tt.next, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package dlog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/memfile"
+	"github.com/dimcz/dlog/timestamp"
+
+	"github.com/nsf/termbox-go"
+)
+
+// split is arrangement of panes on the screen
+type split int
+
+const (
+	splitVertical   split = iota // panes side by side
+	splitHorizontal              // panes one above another
+)
+
+const maxPanes = 4
+
+const paneSeparator = '│'
+
+// pane is a viewer with its own source, memfile, fetcher and filters
+type pane struct {
+	source  Source
+	file    *memfile.File
+	fetcher *Fetcher
+	v       *viewer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+}
+
+// openPane starts following source in a new focused pane
+func (d *Dlog) openPane(source Source, file *memfile.File) {
+	p := &pane{
+		source: source,
+		file:   file,
+		wg:     new(sync.WaitGroup),
+	}
+	p.ctx, p.cancel = context.WithCancel(d.ctx)
+
+	start := source.Follow()
+
+	p.fetcher = NewFetcher(p.ctx, file)
+	_, _ = file.Seek(0, io.SeekStart)
+
+	opts := []ViewOptionsFunc{
+		WithCtx(p.ctx),
+		WithFetcher(p.fetcher),
+		WithWrap(true),
+	}
+	if _, ok := source.(switcher); ok {
+		opts = append(opts,
+			WithKeyArrowRight(p.rightDirection),
+			WithKeyArrowLeft(p.leftDirection))
+	}
+	if s, ok := source.(selector); ok {
+		opts = append(opts, WithContainers(s.Containers, p.selectContainer))
+	}
+	if s, ok := source.(streamer); ok {
+		opts = append(opts, WithStream(s.Stream))
+	}
+
+	p.v = NewViewer(opts...)
+	p.v.setup(source.Name())
+	p.v.logName = source.LogName()
+
+	d.panes = append(d.panes, p)
+	d.focused = len(d.panes) - 1
+	d.layout()
+
+	p.v.run(p.ctx, p.wg, func() {
+		source.Append(start, p.v.refill)
+	})
+	d.assignWatchers()
+}
+
+// close stops source and goroutines of the pane
+func (p *pane) close() {
+	p.cancel()
+	if s, ok := p.source.(stopper); ok {
+		s.Stop()
+	}
+	p.wg.Wait()
+}
+
+func (p *pane) rightDirection() {
+	p.v.initScreen()
+	p.source.(switcher).NextContainer()
+	p.reload()
+}
+
+func (p *pane) leftDirection() {
+	p.v.initScreen()
+	p.source.(switcher).PrevContainer()
+	p.reload()
+}
+
+func (p *pane) selectContainer(i int) {
+	p.v.initScreen()
+	p.source.(selector).SelectContainer(i)
+	p.reload()
+}
+
+func (p *pane) reload() {
+	start := p.source.Follow()
+
+	p.v.setTerminalName(p.source.Name())
+	p.v.logName = p.source.LogName()
+
+	p.v.navigateEnd()
+	p.v.navigateEnd()
+
+	p.source.Append(start, p.v.refill)
+}
+
+// splitPane opens current log of the focused pane in a new one, panes are arranged by s
+func (d *Dlog) splitPane(s split) {
+	v := d.panes[d.focused].v
+	if len(d.panes) > 1 && d.split != s {
+		d.split = s
+		d.layout()
+		return
+	}
+	if len(d.panes) >= maxPanes {
+		v.info.setMessage(ibMessage{str: "Err: too many panes", color: termbox.ColorRed})
+		return
+	}
+
+	file := memfile.New([]byte{})
+	source, ok := splitSource(d.ctx, d.panes[d.focused].source, file)
+	if !ok {
+		v.info.setMessage(ibMessage{str: "Err: log can't be opened twice", color: termbox.ColorRed})
+		return
+	}
+
+	d.split = s
+	d.openPane(source, file)
+}
+
+// closePane closes the focused pane, the last one is closed by quitting
+func (d *Dlog) closePane() {
+	if len(d.panes) == 1 {
+		return
+	}
+
+	d.panes[d.focused].close()
+	d.panes = append(d.panes[:d.focused], d.panes[d.focused+1:]...)
+	if d.focused == len(d.panes) {
+		d.focused--
+	}
+	d.layout()
+	d.assignWatchers()
+}
+
+// assignWatchers lets the first pane of each log evaluate watch rules, so lines of a log
+// shown in several panes fire once. It is called when panes are opened, closed or switch container
+func (d *Dlog) assignWatchers() {
+	watched := make(map[string]bool, len(d.panes))
+	for _, p := range d.panes {
+		var watching int32
+		if !watched[p.v.logName] {
+			watching = 1
+		}
+		atomic.StoreInt32(&p.v.watching, watching)
+		watched[p.v.logName] = true
+	}
+}
+
+// focusPane moves focus to pane i
+func (d *Dlog) focusPane(i int) {
+	d.focused = i
+	for i, p := range d.panes {
+		p.v.info.inactive = i != d.focused
+		p.v.info.draw()
+	}
+	logging.LogOnErr(termbox.Flush())
+}
+
+// layout divides the screen between panes equally
+func (d *Dlog) layout() {
+	logging.LogOnErr(termbox.Clear(termbox.ColorDefault, termbox.ColorDefault))
+
+	width, height := termbox.Size()
+	n := len(d.panes)
+	if d.split == splitVertical {
+		width -= n - 1 // separators
+	}
+
+	for i, p := range d.panes {
+		p.v.info.inactive = i != d.focused
+		switch d.split {
+		case splitVertical:
+			x, end := i*width/n, (i+1)*width/n
+			p.v.place(x+i, 0, end-x, height)
+			if i != n-1 {
+				for y := 0; y < height; y++ {
+					termbox.SetCell(end+i, y, paneSeparator, termbox.ColorDefault, termbox.ColorDefault)
+				}
+			}
+		case splitHorizontal:
+			y, end := i*height/n, (i+1)*height/n
+			p.v.place(0, y, width, end-y)
+		}
+	}
+
+	logging.LogOnErr(termbox.Flush())
+}
+
+// processKey handles keys of the screen, it returns false when key belongs to the focused pane
+func (d *Dlog) processKey(ev termbox.Event) bool {
+	b := lookup(screenKeymap, ev)
+	if b == nil {
+		return false
+	}
+
+	b.do(d, ev)
+	return true
+}
+
+func (d *Dlog) toggleSyncScroll() {
+	d.syncScroll = !d.syncScroll
+	msg := "Scrolling of panes is not synced"
+	if d.syncScroll {
+		msg = "Scrolling of panes is synced by timestamp"
+		d.syncPanes()
+	}
+	d.panes[d.focused].v.info.setMessage(ibMessage{str: msg, color: termbox.ColorGreen})
+}
+
+// syncPanes scrolls other panes to timestamp of the current line of the focused one
+func (d *Dlog) syncPanes() {
+	v := d.panes[d.focused].v
+	if v.following {
+		for _, p := range d.panes {
+			if p.v != v && !p.v.following {
+				p.v.navigateEnd()
+			}
+		}
+		return
+	}
+
+	t, n := timestamp.ParseBytes(v.buffer.currentLine().Text)
+	if n == 0 {
+		return
+	}
+	for _, p := range d.panes {
+		if p.v != v {
+			p.v.jumpToTime(t)
+		}
+	}
+}
+
+// processMouse passes event to the pane under pointer, pressing button focuses the pane.
+// Dragging and releasing are passed to the focused pane, where selection has started
+func (d *Dlog) processMouse(ev termbox.Event) {
+	v := d.panes[d.focused].v
+	if ev.Key != termbox.MouseRelease && ev.Mod&termbox.ModMotion == 0 {
+		for i, p := range d.panes {
+			if ev.MouseX >= p.v.x && ev.MouseX < p.v.x+p.v.width && ev.MouseY >= p.v.y && ev.MouseY < p.v.y+p.v.rows {
+				if ev.Key == termbox.MouseLeft && i != d.focused {
+					d.focusPane(i)
+				}
+				v = p.v
+				break
+			}
+		}
+	}
+
+	h, ok := v.focus.(mouseHandler)
+	if !ok {
+		return
+	}
+	pos, following := v.buffer.currentLine().Pos, v.following
+	if h.processMouse(ev, ev.MouseX-v.x, ev.MouseY-v.y) == ACTION_RESET_FOCUS {
+		v.resetFocus()
+	}
+	if v == d.panes[d.focused].v {
+		d.afterMove(pos, following)
+	}
+}
+
+// afterMove syncs panes when current line of the focused one was changed
+func (d *Dlog) afterMove(pos Pos, following bool) {
+	v := d.panes[d.focused].v
+	if d.syncScroll && (v.buffer.currentLine().Pos != pos || v.following != following) {
+		d.syncPanes()
+	}
+}
+
+// isOpen reports whether v is viewer of an open pane, requests of closed ones are dropped
+func (d *Dlog) isOpen(v *viewer) bool {
+	for _, p := range d.panes {
+		if p.v == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Dlog) loop() {
+	for {
+		switch ev := termbox.PollEvent(); ev.Type {
+		case termbox.EventKey:
+			v := d.panes[d.focused].v
+			if v.focus == v && v.markKey == 0 && d.processKey(ev) {
+				continue
+			}
+
+			pos, following := v.buffer.currentLine().Pos, v.following
+			switch v.focus.processKey(ev) {
+			case ACTION_QUIT:
+				return
+			case ACTION_RESET_FOCUS:
+				v.resetFocus()
+			}
+			d.afterMove(pos, following)
+			d.assignWatchers()
+		case termbox.EventMouse:
+			d.processMouse(ev)
+			d.assignWatchers()
+		case termbox.EventResize:
+			logging.Debug("Resize event", ev.Width, ev.Height)
+			d.layout()
+		case termbox.EventError:
+			panic(ev.Err)
+		case termbox.EventInterrupt:
+			processRequest(d.isOpen)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package pattern masks variable parts of log messages, so similar messages can be compared
+package pattern
+
+import "regexp"
+
+type mask struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// masks are applied in order, more specific ones go first
+var masks = []mask{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-f]{8,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+(\.\d+)?`), "<num>"},
+}
+
+// Mask replaces UUIDs, IP addresses, hex identifiers and numbers with placeholders
+func Mask(s string) string {
+	for _, m := range masks {
+		s = m.re.ReplaceAllString(s, m.repl)
+	}
+
+	return s
+}
+
+var quotedRe = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'[^']*'`)
+
+// Template masks quoted strings in addition to Mask, leaving only constant parts of the message
+func Template(s string) string {
+	return Mask(quotedRe.ReplaceAllString(s, "<str>"))
+}
+
+var placeholders = map[string]string{
+	"<str>":  `(?:"(?:[^"\\]|\\.)*"|'[^']*')`,
+	"<uuid>": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"<ip>":   `\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?`,
+	"<hex>":  `(?:0x[0-9a-fA-F]+|[0-9a-f]{8,})`,
+	"<num>":  `\d+(?:\.\d+)?`,
+}
+
+var placeholderRe = regexp.MustCompile(`<(?:str|uuid|ip|hex|num)>`)
+
+// Regexp returns regular expression matching messages of the template
+func Regexp(template string) string {
+	var re string
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(template, -1) {
+		re += regexp.QuoteMeta(template[last:loc[0]]) + placeholders[template[loc[0]:loc[1]]]
+		last = loc[1]
+	}
+
+	return re + regexp.QuoteMeta(template[last:])
+}
@@ -0,0 +1,82 @@
+package stream
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/memfile"
+)
+
+const (
+	chunkSize    = 64 * 1024
+	historyPause = 200 * time.Millisecond
+)
+
+// Stream feeds logs from a non-seekable reader, e.g. piped stdin
+type Stream struct {
+	file   *memfile.File
+	reader io.Reader
+	name   string
+	once   sync.Once
+}
+
+func New(file *memfile.File, name string, reader io.Reader) *Stream {
+	return &Stream{
+		file:   file,
+		reader: reader,
+		name:   name,
+	}
+}
+
+// Follow starts copying the reader into file. Stream can be consumed only once,
+// so repeated calls keep already received data. No timestamp is known in advance.
+func (s *Stream) Follow() int64 {
+	s.once.Do(func() {
+		s.file.Clear()
+
+		logging.Debug("execute stream process", s.name)
+		go s.copy()
+	})
+
+	return -1
+}
+
+// Append does nothing, stream has no history to load
+func (s *Stream) Append(int64, func()) {}
+
+func (s *Stream) Name() string {
+	return s.name
+}
+
+func (s *Stream) LogName() string {
+	return s.name
+}
+
+// copy writes the reader into file. Data piped at once, like history printed by kubectl logs -f,
+// is not live, live data starts with the first read waiting for historyPause
+func (s *Stream) copy() {
+	defer logging.Timeit("stream", s.name)()
+
+	buf := make([]byte, chunkSize)
+	live := false
+	for {
+		start := time.Now()
+		n, err := s.reader.Read(buf)
+		if !live && time.Since(start) >= historyPause {
+			s.file.Live()
+			live = true
+		}
+		if n > 0 {
+			_, werr := s.file.Write(buf[:n])
+			logging.LogOnErr(werr)
+		}
+		if err != nil {
+			if err != io.EOF {
+				logging.Debug(err)
+			}
+			return
+		}
+	}
+}
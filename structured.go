@@ -0,0 +1,223 @@
+package dlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dimcz/dlog/ansi"
+	"github.com/dimcz/dlog/config"
+	"github.com/dimcz/dlog/fields"
+	"github.com/dimcz/dlog/level"
+)
+
+var (
+	jsonLeadingOnce sync.Once
+	jsonLeading     [][]string
+)
+
+// leadingFields returns groups of alternative keys, which values are shown first without key in compact form.
+// They are parsed on first use, as flags are not parsed yet during initialization of package
+func leadingFields() [][]string {
+	jsonLeadingOnce.Do(func() {
+		jsonLeading = parseLeadingFields(config.GetValue().JSONFields)
+	})
+
+	return jsonLeading
+}
+
+var jsonKeyAttr = ansi.RuneAttr{Fg: ansi.FgColor(ansi.ColorCyan)}
+
+// parseLeadingFields splits definition like "level|lvl,msg|message" into groups of alternatives
+func parseLeadingFields(s string) (groups [][]string) {
+	for _, group := range strings.Split(s, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, strings.Split(group, "|"))
+		}
+	}
+
+	return groups
+}
+
+// compactJSON renders JSON object payload as `LEVEL msg key=value...`, false if payload is not JSON
+// or compact form is disabled by empty -jsonfields. Index of payload rune each rune is rendered from
+// is returned along, -1 for runes added by rendering, so matches in payload can be highlighted
+func compactJSON(payload ansi.Astring) (ansi.Astring, []int, bool) {
+	if len(leadingFields()) == 0 {
+		return payload, nil, false
+	}
+
+	parsed, ok := fields.ParseJSON([]byte(string(payload.Runes)))
+	if !ok {
+		return payload, nil, false
+	}
+	keys, values := jsonSpans(payload.Runes)
+	if len(keys) != len(parsed) {
+		keys, values = make([]span, len(parsed)), make([]span, len(parsed))
+	}
+
+	var out ansi.Astring
+	var src []int
+	used := make([]bool, len(parsed))
+	appendString := func(s string, attr ansi.RuneAttr, from span) {
+		out.AppendString(s, attr)
+		src = append(src, sourceOf([]rune(s), payload.Runes, from)...)
+	}
+	add := func(s string, attr ansi.RuneAttr, from span) {
+		if len(out.Runes) != 0 {
+			appendString(" ", ansi.RuneAttr{}, span{})
+		}
+		appendString(s, attr, from)
+	}
+
+	for _, group := range leadingFields() {
+	lookup:
+		for _, key := range group {
+			for i, f := range parsed {
+				if used[i] || f.Key != key {
+					continue
+				}
+				used[i] = true
+				value := f.Value
+				if level.IsKey(key) {
+					value = strings.ToUpper(value)
+				}
+				add(value, ansi.RuneAttr{}, values[i])
+				break lookup
+			}
+		}
+	}
+
+	for i, f := range parsed {
+		if used[i] {
+			continue
+		}
+		add(f.Key, jsonKeyAttr, keys[i])
+		appendString("=", ansi.RuneAttr{}, span{})
+		appendString(quoteValue(f), ansi.RuneAttr{}, values[i])
+	}
+
+	return out, src, true
+}
+
+func quoteValue(f fields.Field) string {
+	if f.Kind == fields.KindString && (f.Value == "" || strings.ContainsAny(f.Value, " \"=")) {
+		return strconv.Quote(f.Value)
+	}
+
+	return f.Value
+}
+
+// expandJSON renders JSON payload indented over several lines, false if payload is not JSON.
+// Index of payload rune each rune is rendered from is returned along, -1 for added indentation
+func expandJSON(payload ansi.Astring) (ansi.Astring, []int, bool) {
+	b := []byte(string(payload.Runes))
+	if !fields.IsJSON(b) {
+		return payload, nil, false
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(b), "", "  "); err != nil {
+		return payload, nil, false
+	}
+
+	var out ansi.Astring
+	for i, line := range strings.Split(indented.String(), "\n") {
+		if i != 0 {
+			out.AppendString("\n", ansi.RuneAttr{})
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if k := strings.Index(trimmed, "\": "); strings.HasPrefix(trimmed, "\"") && k > 0 {
+			keyEnd := len(line) - len(trimmed) + k + 1
+			out.AppendString(line[:keyEnd], jsonKeyAttr)
+			line = line[keyEnd:]
+		}
+		out.AppendString(line, ansi.RuneAttr{})
+	}
+
+	return out, indentSource(out.Runes, payload.Runes), true
+}
+
+// span is range of runes [start, end), empty one stands for runes added by rendering
+type span struct {
+	start, end int
+}
+
+// jsonSpans returns spans of keys and raw values of fields of JSON object in payload,
+// in the order fields.ParseJSON returns them. Keys are spanned with their quotes
+func jsonSpans(payload []rune) (keys, values []span) {
+	b := []byte(string(payload))
+	var runeIndex []int // rune index of each byte of b
+	for i, r := range payload {
+		for range string(r) {
+			runeIndex = append(runeIndex, i)
+		}
+	}
+	runeIndex = append(runeIndex, len(payload))
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil
+	}
+	for dec.More() {
+		prev := int(dec.InputOffset())
+		if _, err := dec.Token(); err != nil {
+			return nil, nil
+		}
+		keyEnd := int(dec.InputOffset())
+		keyStart := prev + bytes.IndexByte(b[prev:keyEnd], '"')
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil
+		}
+		valueEnd := int(dec.InputOffset())
+		keys = append(keys, span{runeIndex[keyStart], runeIndex[keyEnd]})
+		values = append(values, span{runeIndex[valueEnd-len(raw)], runeIndex[valueEnd]})
+	}
+
+	return keys, values
+}
+
+// sourceOf returns index of payload rune each of shown runes is rendered from. Shown runes are the raw
+// text of span, possibly unquoted and in other case, otherwise they are considered added by rendering
+func sourceOf(shown, payload []rune, from span) []int {
+	src := make([]int, len(shown))
+	raw := payload[from.start:from.end]
+	start := from.start
+	if len(raw) == len(shown)+2 && raw[0] == '"' {
+		raw, start = raw[1:len(raw)-1], start+1
+	}
+	if len(raw) != len(shown) || !strings.EqualFold(string(raw), string(shown)) {
+		start = -1
+	}
+	for i := range src {
+		src[i] = -1
+		if start >= 0 {
+			src[i] = start + i
+		}
+	}
+
+	return src
+}
+
+// indentSource returns index of payload rune each rune of its indented form is rendered from,
+// as indentation only changes whitespace between tokens, runes of both are matched in order
+func indentSource(indented, payload []rune) []int {
+	src := make([]int, len(indented))
+	j := 0
+	for i, r := range indented {
+		for j < len(payload) && r != payload[j] && unicode.IsSpace(payload[j]) {
+			j++
+		}
+		src[i] = -1
+		if j < len(payload) && r == payload[j] {
+			src[i] = j
+			j++
+		}
+	}
+
+	return src
+}
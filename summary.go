@@ -0,0 +1,265 @@
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/logging"
+	"github.com/dimcz/dlog/pattern"
+	"github.com/dimcz/dlog/runes"
+	"github.com/dimcz/dlog/timestamp"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+type cluster struct {
+	template    string
+	count       int
+	first, last time.Time
+}
+
+// summaryView is an overlay listing templates of lines, i.e. lines with variable tokens masked,
+// with their count and time range. Selected template can be turned into a filter.
+// Lines are clustered in background, progress is shown until it is done
+type summaryView struct {
+	v        *viewer
+	cancel   context.CancelFunc // stops clustering when summary is closed
+	progress summaryProgress
+	clusters []cluster
+	total    int
+	selected int
+	top      int
+}
+
+// summaryProgress is state of clustering, clusters are delivered along when it is done
+type summaryProgress struct {
+	s        *summaryView
+	lines    int
+	percent  int
+	done     bool
+	clusters []cluster
+	total    int
+}
+
+const summaryProgressInterval = 100 * time.Millisecond
+
+func newSummaryView(v *viewer) *summaryView {
+	ctx, cancel := context.WithCancel(v.ctx)
+	s := &summaryView{v: v, cancel: cancel}
+	size := len(v.fetcher.reader.Bytes())
+	go func() {
+		defer logging.Timeit("clustering")()
+		defer cancel()
+		report := func(p summaryProgress) {
+			p.s = s
+			go termbox.Interrupt()
+			select {
+			case requestSummary <- p:
+			case <-ctx.Done():
+			}
+		}
+		reportedAt := time.Now()
+		clusters, total := clusterLines(ctx, v.fetcher, func(lines int, offset Offset) {
+			if time.Since(reportedAt) < summaryProgressInterval || size == 0 {
+				return
+			}
+			reportedAt = time.Now()
+			report(summaryProgress{lines: lines, percent: int(int64(offset) * 100 / int64(size))})
+		})
+		if ctx.Err() == nil {
+			report(summaryProgress{lines: total, percent: 100, done: true, clusters: clusters, total: total})
+		}
+	}()
+
+	return s
+}
+
+// clusterLines groups all lines by their templates, most frequent first. Progress is called
+// after each line with number of lines clustered and offset of the line
+func clusterLines(ctx context.Context, f *Fetcher, progress func(lines int, offset Offset)) ([]cluster, int) {
+	byTemplate := make(map[string]*cluster)
+	total := 0
+	for l := range f.Get(ctx, Pos{0, 0}) {
+		t, n := timestamp.Parse(l.Str.Runes)
+		payload := l.Str.Runes[n:]
+		if i := runes.IndexRune(payload, '\n'); i != -1 {
+			payload = payload[:i] // multi-line entries are clustered by first line
+		}
+
+		template := pattern.Template(string(payload))
+		c, ok := byTemplate[template]
+		if !ok {
+			c = &cluster{template: template, first: t}
+			byTemplate[template] = c
+		}
+		c.count += repeated(l)
+		c.last = t
+		total += repeated(l)
+		progress(total, l.Offset)
+	}
+
+	clusters := make([]cluster, 0, len(byTemplate))
+	for _, c := range byTemplate {
+		clusters = append(clusters, *c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].count == clusters[j].count {
+			return clusters[i].template < clusters[j].template
+		}
+		return clusters[i].count > clusters[j].count
+	})
+
+	return clusters, total
+}
+
+// update shows progress of clustering, or its result when it is done
+func (s *summaryView) update(p summaryProgress) {
+	s.progress = p
+	if p.done {
+		s.clusters, s.total = p.clusters, p.total
+	}
+	s.draw()
+}
+
+// printRow fills whole row of overlay with str
+func (v *viewer) printRow(y int, str string, fg, bg termbox.Attribute) {
+	x := 0
+	for _, r := range str {
+		if x >= v.width {
+			break
+		}
+		v.setCell(x, y, r, fg, bg)
+		x += runewidth.RuneWidth(r)
+	}
+	for ; x < v.width; x++ {
+		v.setCell(x, y, ' ', fg, bg)
+	}
+}
+
+func (s *summaryView) draw() {
+	s.v.clear()
+
+	if !s.progress.done {
+		s.v.printRow(0, fmt.Sprintf("Clustering... %d lines, %d%%. q: cancel", s.progress.lines, s.progress.percent),
+			termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+		logging.LogOnErr(termbox.Flush())
+		return
+	}
+
+	s.v.printRow(0, fmt.Sprintf("%d patterns in %d lines. Enter,&: include +: union -: exclude ~: highlight q: close",
+		len(s.clusters), s.total), termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+
+	rows := s.v.height - 1 // the first row is header
+	if s.selected < s.top {
+		s.top = s.selected
+	}
+	if s.selected >= s.top+rows {
+		s.top = s.selected - rows + 1
+	}
+
+	for y := 0; y < rows && s.top+y < len(s.clusters); y++ {
+		c := s.clusters[s.top+y]
+		fg, bg := termbox.ColorDefault, termbox.ColorDefault
+		if s.top+y == s.selected {
+			fg |= termbox.AttrReverse
+		}
+		s.v.printRow(y+1, fmt.Sprintf("%8d  %s  %s", c.count, timeRange(c.first, c.last), c.template), fg, bg)
+	}
+
+	logging.LogOnErr(termbox.Flush())
+}
+
+func timeRange(first, last time.Time) string {
+	if first.IsZero() {
+		return "-"
+	}
+
+	return first.Format("01-02 15:04:05") + ".." + last.Format("01-02 15:04:05")
+}
+
+func (s *summaryView) move(direction int) {
+	s.selected += direction
+	if s.selected >= len(s.clusters) {
+		s.selected = len(s.clusters) - 1
+	}
+	if s.selected < 0 {
+		s.selected = 0
+	}
+	s.draw()
+}
+
+// addFilter turns selected template into filter, matching lines with optional timestamp in front
+func (s *summaryView) addFilter(action filters.FilterAction) action {
+	if len(s.clusters) == 0 {
+		return ACTION_RESET_FOCUS
+	}
+
+	re := `(?s)^(?:\S+ )?` + pattern.Regexp(s.clusters[s.selected].template) + `(?:\n.*)?$`
+	filter, err := filters.NewFilter([]rune(re), action, filters.RegEx)
+	if err != nil {
+		logging.Debug(err)
+		return ACTION_RESET_FOCUS
+	}
+	s.v.applyFilter(filter)
+
+	return ACTION_RESET_FOCUS
+}
+
+// close stops clustering if it is not done yet
+func (s *summaryView) close() action {
+	s.cancel()
+
+	return ACTION_RESET_FOCUS
+}
+
+func (s *summaryView) processKey(ev termbox.Event) action {
+	if !s.progress.done {
+		if ev.Ch == 'q' || ev.Key == termbox.KeyEsc {
+			return s.close()
+		}
+		return NO_ACTION
+	}
+	if ev.Ch != 0 {
+		if filterAction, ok := filters.FilterActionMap[ev.Ch]; ok {
+			return s.addFilter(filterAction)
+		}
+		switch ev.Ch {
+		case 'q':
+			return ACTION_RESET_FOCUS
+		case 'j':
+			s.move(+1)
+		case 'k':
+			s.move(-1)
+		case 'g':
+			s.move(-len(s.clusters))
+		case 'G':
+			s.move(len(s.clusters))
+		}
+		return NO_ACTION
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return ACTION_RESET_FOCUS
+	case termbox.KeyEnter:
+		return s.addFilter(filters.FilterIntersect)
+	case termbox.KeyArrowDown:
+		s.move(+1)
+	case termbox.KeyArrowUp:
+		s.move(-1)
+	case termbox.KeyPgdn, termbox.KeySpace, termbox.KeyCtrlF:
+		s.move(+s.v.height)
+	case termbox.KeyPgup, termbox.KeyCtrlB:
+		s.move(-s.v.height)
+	case termbox.KeyHome:
+		s.move(-len(s.clusters))
+	case termbox.KeyEnd:
+		s.move(len(s.clusters))
+	}
+
+	return NO_ACTION
+}
@@ -2,6 +2,7 @@ package dlog
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -13,19 +14,23 @@ import (
 
 	"github.com/dimcz/dlog/ansi"
 	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/level"
 	"github.com/dimcz/dlog/logging"
 	"github.com/dimcz/dlog/runes"
+	"github.com/dimcz/dlog/timestamp"
 	"github.com/dimcz/dlog/utils"
 
 	"code.cloudfoundry.org/bytefmt"
-	"github.com/mattn/go-runewidth"
 	"github.com/nsf/termbox-go"
 )
 
 type viewer struct {
 	hOffset       int
+	x             int // position of the pane on the screen
+	y             int
 	width         int
-	height        int
+	height        int // rows of lines
+	rows          int // rows of the pane including infobar
 	sizeLock      sync.Mutex
 	wrap          bool
 	fetcher       *Fetcher
@@ -37,6 +42,30 @@ type viewer struct {
 	keepChars     int
 	ctx           context.Context
 	following     bool
+	paused        bool // following is stopped by user, it is not resumed by scrolling to the end
+	levelFilter   *filters.Filter
+	expanded      map[Offset]bool // lines shown as indented multi-line JSON
+	marksVersion  int64           // version of memfile offsets of marked lines are valid for
+	folded        map[Offset]bool // multi-line entries shown by first line only
+	sparkline     bool            // line rate is shown in a row above infobar
+	histogram     *histogram
+	histogramFor  histogramKey
+	histogramAt   time.Time
+	histogramBusy bool
+	rates         rateCounter // lines counted for histogram, it is used by one histogram goroutine at a time
+
+	lastLineControl chan struct{}
+
+	logName  string                    // bookmarks are kept by it
+	watching int32                     // 1 when watch rules are evaluated by this pane, one pane of each log does it
+	stream   func(offset int64) string // stream of line at offset, nil when source doesn't know it
+	markKey  rune                      // m or ' waiting for name of bookmark
+
+	rowLines        []int      // buffer line shown on each row of the screen
+	selection       *selection // text selected by mouse
+	selected        Offset     // offset of line selected by click, -1 when there is none
+	containers      func() ([]string, int)
+	selectContainer func(i int)
 
 	keyArrowRight func()
 	keyArrowLeft  func()
@@ -58,6 +87,7 @@ const (
 )
 
 type View interface {
+	draw()
 }
 
 type Focusing interface {
@@ -102,8 +132,24 @@ func WithKeyArrowLeft(f func()) ViewOptionsFunc {
 	}
 }
 
+func WithContainers(list func() ([]string, int), selectContainer func(i int)) ViewOptionsFunc {
+	return func(v *viewer) {
+		v.containers = list
+		v.selectContainer = selectContainer
+	}
+}
+
+func WithStream(f func(offset int64) string) ViewOptionsFunc {
+	return func(v *viewer) {
+		v.stream = f
+	}
+}
+
 func NewViewer(opts ...ViewOptionsFunc) *viewer {
-	v := &viewer{}
+	v := &viewer{
+		lastLineControl: make(chan struct{}),
+		selected:        -1,
+	}
 	for _, opt := range opts {
 		opt(v)
 	}
@@ -126,6 +172,7 @@ func (v *viewer) setTerminalName(name string) {
 func (v *viewer) searchForward() {
 	searchFunc, err := filters.GetSearchFunc(v.info.searchType, v.search)
 	if err != nil {
+		v.info.setMessage(ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed})
 		return
 	}
 	if distance := v.buffer.searchForward(searchFunc); distance != -1 {
@@ -154,6 +201,7 @@ func (v *viewer) searchHighlighted() {
 func (v *viewer) searchBack() {
 	searchFunc, err := filters.GetSearchFunc(v.info.searchType, v.search)
 	if err != nil {
+		v.info.setMessage(ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed})
 		return
 	}
 	if distance := v.buffer.searchBack(searchFunc); distance != -1 {
@@ -203,6 +251,7 @@ func (v *viewer) nextSearch(reverse bool) {
 func (v *viewer) applyFilter(filter *filters.Filter) {
 	v.fetcher.lock.Lock()
 	v.fetcher.filters = append(v.fetcher.filters, filter)
+	v.fetcher.filtersVersion++
 	v.fetcher.filtersEnabled = true
 	v.buffer.reset(v.buffer.currentLine().Pos)
 	v.fetcher.lock.Unlock()
@@ -212,6 +261,7 @@ func (v *viewer) addFilter(sub []rune, action filters.FilterAction) {
 	filter, err := filters.NewFilter(sub, action, v.info.searchType)
 	if err != nil {
 		logging.Debug(err)
+		v.info.setMessage(ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed})
 		return
 	}
 	v.applyFilter(filter)
@@ -223,72 +273,177 @@ func (v *viewer) switchFilters() {
 	v.draw()
 }
 
-var stylesMap = map[uint8]termbox.Attribute{
-	1: termbox.AttrBold,
-	7: termbox.AttrReverse,
+// levelAttrs colors lines by detected level, only runes without own color are affected
+var levelAttrs = map[level.Level]ansi.RuneAttr{
+	level.Trace: {Fg: ansi.FgColor(ansi.ColorBlue)},
+	level.Debug: {Fg: ansi.FgColor(ansi.ColorCyan)},
+	level.Warn:  {Fg: ansi.FgColor(ansi.ColorYellow)},
+	level.Error: {Fg: ansi.FgColor(ansi.ColorRed)},
+	level.Fatal: {Fg: ansi.FgColor(ansi.ColorMagenta), Style: ansi.StyleBold},
 }
 
-func (v *viewer) replaceWithKeptChars(data ansi.Astring) ([]rune, []ansi.RuneAttr) {
-	dataLen := len(data.Runes)
-	if v.keepChars <= 0 || v.wrap {
-		sliceFromIdx := utils.Min(v.hOffset, dataLen)
-		return data.Runes[sliceFromIdx:], data.Attrs[sliceFromIdx:]
+// setLevelFilter replaces previous level filter, level.Unknown only removes it
+func (v *viewer) setLevelFilter(min level.Level) {
+	v.fetcher.lock.Lock()
+	for i, filter := range v.fetcher.filters {
+		if filter == v.levelFilter {
+			v.fetcher.filters = append(v.fetcher.filters[:i], v.fetcher.filters[i+1:]...)
+			v.fetcher.filtersVersion++
+			break
+		}
+	}
+	v.levelFilter = nil
+	v.fetcher.lock.Unlock()
+
+	if min == level.Unknown {
+		v.buffer.refresh()
+		v.draw()
+		v.info.setMessage(ibMessage{str: "Level filter removed", color: termbox.ColorGreen})
+		return
+	}
+
+	v.levelFilter = filters.NewLevelFilter(min)
+	v.applyFilter(v.levelFilter)
+	v.draw()
+	v.info.setMessage(ibMessage{str: "Level >= " + min.String(), color: termbox.ColorGreen})
+}
+
+// highlightedLineBg is background of lines marked with backtick
+var highlightedLineBg = ansi.PaletteColor(32)
+
+// selectedLineBg is background of line selected by click
+var selectedLineBg = ansi.PaletteColor(236)
+
+var stylesMap = map[ansi.Style]termbox.Attribute{
+	ansi.StyleBold:      termbox.AttrBold,
+	ansi.StyleDim:       termbox.AttrDim,
+	ansi.StyleItalic:    termbox.AttrCursive,
+	ansi.StyleUnderline: termbox.AttrUnderline,
+	ansi.StyleBlink:     termbox.AttrBlink,
+	ansi.StyleReverse:   termbox.AttrReverse,
+	ansi.StyleHidden:    termbox.AttrHidden,
+}
+
+// renderLine returns entry as it is shown, without time stamp, with JSON reformatted and counter of
+// duplicates added, along with index of rune of line each shown rune comes from, -1 for added runes
+func (v *viewer) renderLine(line Line) (ansi.Astring, []int) {
+	str := line.Str
+	src := make([]int, len(str.Runes))
+	for i := range src {
+		src[i] = i
+	}
+	if _, i := timestamp.Parse(str.Runes); i > 0 {
+		str = ansi.Astring{Runes: str.Runes[i:], Attrs: str.Attrs[i:], Links: str.Links}
+		src = src[i:]
+	}
+	var rendered []int
+	if v.expanded[line.Offset] {
+		str, rendered, _ = expandJSON(str)
+	} else {
+		str, rendered, _ = compactJSON(str)
 	}
+	src = sourceIndex(src, rendered)
+	if v.folded[line.Offset] {
+		folded := foldEntry(str)
+		src, str = appendedIndex(src, str.Runes, folded.Runes), folded
+	}
+	if line.Repeated > 1 {
+		repeated := appendRepeats(str, line)
+		src, str = appendedIndex(src, str.Runes, repeated.Runes), repeated
+	}
+
+	return str, src
+}
 
+// replaceWithKeptChars applies horizontal offset and kept columns to each line of multi-line entry,
+// src holds index of source rune of each rune and is cut along with them
+func (v *viewer) replaceWithKeptChars(data ansi.Astring, src []int) ([]rune, []ansi.RuneAttr, []int) {
 	var chars []rune
 	var attrs []ansi.RuneAttr
+	var srcs []int
 
-	if dataLen > v.keepChars {
-		chars = make([]rune, v.keepChars, dataLen)
-		attrs = make([]ansi.RuneAttr, v.keepChars, dataLen)
-		copy(chars, data.Runes[:v.keepChars])
-		copy(attrs, data.Attrs[:v.keepChars])
+	start := 0
+	for i := 0; i <= len(data.Runes); i++ {
+		if i < len(data.Runes) && data.Runes[i] != '\n' {
+			continue
+		}
+		c, a, s := v.keepColumns(data.Runes[start:i], data.Attrs[start:i], src[start:i])
+		if start == 0 && i == len(data.Runes) {
+			return c, a, s // single line
+		}
+		chars, attrs, srcs = append(chars, c...), append(attrs, a...), append(srcs, s...)
+		if i < len(data.Runes) {
+			chars, attrs, srcs = append(chars, '\n'), append(attrs, data.Attrs[i]), append(srcs, src[i])
+		}
+		start = i + 1
+	}
+
+	return chars, attrs, srcs
+}
 
-		rightSliceBegin := utils.Min(v.keepChars+v.hOffset, dataLen)
-		chars = append(chars, data.Runes[rightSliceBegin:]...)
-		attrs = append(attrs, data.Attrs[rightSliceBegin:]...)
+// keepColumns scrolls line horizontally by hOffset columns, keeping the first keepChars columns in place
+func (v *viewer) keepColumns(runes []rune, runeAttrs []ansi.RuneAttr, src []int) ([]rune, []ansi.RuneAttr, []int) {
+	dataLen := len(runes)
+	if v.keepChars <= 0 || v.wrap {
+		sliceFromIdx := columnIndex(runes, v.hOffset)
+		return runes[sliceFromIdx:], runeAttrs[sliceFromIdx:], src[sliceFromIdx:]
+	}
+
+	var chars []rune
+	var attrs []ansi.RuneAttr
+	var srcs []int
+
+	kept := columnIndex(runes, v.keepChars)
+	if dataLen > kept {
+		chars = make([]rune, kept, dataLen)
+		attrs = make([]ansi.RuneAttr, kept, dataLen)
+		copy(chars, runes[:kept])
+		copy(attrs, runeAttrs[:kept])
+		srcs = append(srcs, src[:kept]...)
+
+		rightSliceBegin := columnIndex(runes, v.keepChars+v.hOffset)
+		chars = append(chars, runes[rightSliceBegin:]...)
+		attrs = append(attrs, runeAttrs[rightSliceBegin:]...)
+		srcs = append(srcs, src[rightSliceBegin:]...)
 	} else {
 		chars = make([]rune, dataLen)
 		attrs = make([]ansi.RuneAttr, dataLen)
-		copy(chars, data.Runes)
-		copy(attrs, data.Attrs)
+		copy(chars, runes)
+		copy(attrs, runeAttrs)
+		srcs = src
 	}
-	for i := 0; i < v.keepChars && i < len(chars); i++ {
+	for i := 0; i < kept && i < len(chars); i++ {
 		attr := &attrs[i]
 		attr.Fg = ansi.FgColor(ansi.ColorBlue)
 		// attr.Bg = ansi.BgColor(ansi.ColorBlue)
-		// attr.Style = uint8(ansi.StyleBold)
+		// attr.Style = ansi.StyleBold
 	}
-	return chars, attrs
+	return chars, attrs, srcs
 }
 
+// ToTermboxAttr converts attributes for Output256 mode. 24-bit colors are approximated by 256 colors palette,
+// as termbox in OutputRGB mode requires all colors, including ones of infobar, to be RGB and draws
+// styled text of default color black
 func ToTermboxAttr(attr ansi.RuneAttr) (fg, bg termbox.Attribute) {
-	style := stylesMap[attr.Style]
-
-	// For "standard" 3-bit colors, convert to termbox attribute value (0-7)
-	// If bold attribute is set, shift the color value +8 for high intensity
-	// color AND continue to set the bold attribute before returning
-	if attr.Fg >= 30 && attr.Fg <= 37 {
-		fg = termbox.Attribute(attr.Fg - 30 + 1)
-		if style == termbox.AttrBold {
-			fg |= 1 << 3
-		}
-	}
-	if attr.Bg >= 40 && attr.Bg <= 47 {
-		bg = termbox.Attribute(attr.Bg - 40 + 1)
-		if style == termbox.AttrBold {
-			bg |= 1 << 3
+	var style termbox.Attribute
+	for s, a := range stylesMap {
+		if attr.Style&s != 0 {
+			style |= a
 		}
 	}
 
-	// if none of the above conditions were matched, the attr color values are
-	// either 0 or a specific color code between 16-255, in which case we can
-	// use them unaltered
-	if fg == 0 {
-		fg = termbox.Attribute(attr.Fg)
+	// For basic colors set by 30-37 and 40-47, if bold attribute is set, use high intensity color
+	// AND continue to set the bold attribute before returning
+	bold := attr.Style&ansi.StyleBold != 0
+	if attr.Fg != 0 {
+		fg = toTermboxColor(attr.Fg, bold)
 	}
-	if bg == 0 {
-		bg = termbox.Attribute(attr.Bg)
+	if attr.Bg != 0 {
+		bg = toTermboxColor(attr.Bg, bold)
+	}
+
+	if attr.Link != 0 {
+		style |= termbox.AttrUnderline
 	}
 
 	fg |= style
@@ -296,6 +451,16 @@ func ToTermboxAttr(attr ansi.RuneAttr) (fg, bg termbox.Attribute) {
 	return fg, bg
 }
 
+// toTermboxColor converts color to termbox one of Output256 mode, basic colors are brightened by bold
+func toTermboxColor(color uint32, bold bool) termbox.Attribute {
+	index := ansi.Index256(color)
+	if bold && color&ansi.ColorBasic != 0 && index < uint8(ansi.ColorBright) {
+		index += uint8(ansi.ColorBright)
+	}
+
+	return termbox.Attribute(index) + 1
+}
+
 type TerminalCell struct {
 	x    int
 	char rune
@@ -305,73 +470,85 @@ type TerminalCell struct {
 
 type CellsBuffer map[int][]TerminalCell
 
-func (v *viewer) fillBuffer() CellsBuffer {
+// fillBuffer returns rows of cells and index of buffer line each row belongs to
+func (v *viewer) fillBuffer() (CellsBuffer, []int) {
+	v.rebaseMarks()
+
 	var chars []rune
 	var attrs []ansi.RuneAttr
 	var attr ansi.RuneAttr
 	var highlightStyle termbox.Attribute
-	var hlIndices [][]int
-	var hlChars int
 	var tx int
 
 	cells := make(CellsBuffer, v.height)
+	var rowLines []int
+	var indexAll filters.IndexAllFunc // compiled once, not for each line
+	if len(v.search) != 0 {
+		indexAll, _ = filters.GetIndexAllFunc(v.info.searchType, v.search)
+	}
 
 	for cellIndex, dataLine, ty := 0, 0, 0; ty < v.height; ty++ {
 		tx = 0
-		hlChars = 0
 		line, err := v.buffer.getLine(dataLine)
 		if err == io.EOF {
 			break
 		}
-		chars, attrs = v.replaceWithKeptChars(line.Str)
-
-		// remove time stamp in beginning of line
-		if i := runes.IndexRune(chars, ' '); i > 0 {
-			chars = chars[i+1:]
-		}
-
-		hlIndices = [][]int{}
-		if len(v.search) != 0 {
-			searchFunc, err := filters.GetSearchFunc(v.info.searchType, v.search)
-			if err == nil {
-				hlIndices = filters.IndexAll(searchFunc, chars)
+		lineAttr := levelAttrs[line.Level()]
+
+		str, src := v.renderLine(line)
+		chars, attrs, src = v.replaceWithKeptChars(str, src)
+		hl := highlightedRunes(indexAll, line.Str.Runes, chars, src)
+
+		clipped := false // the rest of unwrapped line doesn't fit, it is skipped up to the next line of entry
+		for _, gr := range graphemes(chars) {
+			char := clusterRune(chars[gr.start:gr.end])
+			if char == '\n' {
+				tx = 0
+				cellIndex++
+				clipped = false
+				continue
 			}
-		}
-		for i, char := range chars {
-			attr = attrs[i]
-			highlightStyle = termbox.Attribute(0)
-			if len(hlIndices) != 0 && hlChars == 0 {
-				if hlIndices[0][0] == i {
-					hlChars = hlIndices[0][1] - hlIndices[0][0]
-					hlIndices = hlIndices[1:]
+			if gr.width == 0 || clipped {
+				continue
+			}
+			if char < ' ' {
+				char = ' '
+			}
+			if tx+gr.width > v.width {
+				if !v.wrap {
+					clipped = true
+					continue
 				}
+				tx = 0
+				cellIndex++
 			}
-			if hlChars != 0 {
-				highlightStyle = termbox.AttrReverse
-				hlChars--
+			attr = attrs[gr.start]
+			if attr.Fg == 0 && attr.Style == 0 {
+				attr.Fg, attr.Style = lineAttr.Fg, lineAttr.Style
+			}
+			highlightStyle = termbox.Attribute(0)
+			for _, h := range hl[gr.start:gr.end] {
+				if h {
+					highlightStyle = termbox.AttrReverse
+				}
 			}
 			if line.Highlighted {
 				highlightStyle |= termbox.AttrUnderline
-				attr.Bg |= ansi.FgColor(ansi.ColorYellow)
+				attr.Bg = highlightedLineBg
+			} else if line.Offset == v.selected && attr.Bg == 0 {
+				attr.Bg = selectedLineBg
 			}
 
 			fg, bg := ToTermboxAttr(attr)
-
 			if highlightStyle != termbox.Attribute(0) {
 				fg |= highlightStyle
 			}
 
 			cells[cellIndex] = append(cells[cellIndex], TerminalCell{tx, char, fg, bg})
-
-			tx += runewidth.RuneWidth(char)
-			if tx >= v.width {
-				if v.wrap {
-					tx = 0
-					cellIndex++
-				} else {
-					break
-				}
-			}
+			tx += gr.width
+		}
+		for len(rowLines) <= cellIndex {
+			rowLines = append(rowLines, dataLine)
 		}
 		if ty >= v.height {
 			break
@@ -380,45 +557,89 @@ func (v *viewer) fillBuffer() CellsBuffer {
 		dataLine++
 	}
 
-	return cells
+	return cells, rowLines
 }
 
 func (v *viewer) draw() {
-	logging.LogOnErr(termbox.Clear(termbox.ColorDefault, termbox.ColorDefault))
+	if o := v.overlay(); o != nil {
+		o.draw()
+		return
+	}
+
+	v.clear()
 
-	buffer := v.fillBuffer()
+	buffer, rowLines := v.fillBuffer()
 
 	offset := len(buffer) - v.height
 	logging.Debug("-->draw:", len(buffer), v.height, offset)
 	if offset < 0 || v.direction == DirectionUP {
 		offset = 0
 	}
+	if offset < len(rowLines) {
+		v.rowLines = rowLines[offset:]
+	} else {
+		v.rowLines = nil
+	}
 
 	for ty := 0; ty < v.height; ty++ {
 		for _, cell := range buffer[ty+offset] {
-			termbox.SetCell(cell.x, ty, cell.char, cell.fg, cell.bg)
+			v.setCell(cell.x, ty, cell.char, cell.fg, cell.bg)
 		}
 	}
 
+	if v.selection != nil && v.selection.dragged {
+		v.drawSelection()
+	}
+
+	if v.sparkline {
+		v.refreshHistogram()
+		v.drawSparkline()
+	}
+
 	v.info.draw()
 
 	logging.LogOnErr(termbox.Flush())
 }
 
+// setCell sets cell of the pane, x and y are relative to its position
+func (v *viewer) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(v.x+x, v.y+y, ch, fg, bg)
+}
+
+// clear fills the pane with spaces, other panes are kept
+func (v *viewer) clear() {
+	for y := 0; y < v.rows; y++ {
+		for x := 0; x < v.width; x++ {
+			v.setCell(x, y, ' ', termbox.ColorDefault, termbox.ColorDefault)
+		}
+	}
+}
+
 func (v *viewer) navigate(direction int) {
 	v.buffer.shift(direction)
-	v.following = false
-	if !v.buffer.isFull() {
-		v.following = true
-	}
+	v.following = !v.buffer.isFull() && !v.paused
 	v.draw()
 }
 
 func (v *viewer) navigateEnd() {
 	v.direction = DirectionDown
+	v.paused = false
 	v.buffer.reset(Pos{POS_UNKNOWN, v.fetcher.lastOffset()})
 	v.navigate(-v.height)
 	v.following = true
+	v.info.draw()
+}
+
+// togglePause stops following of new lines or resumes it, jumping to the end
+func (v *viewer) togglePause() {
+	if v.paused || !v.following {
+		v.navigateEnd()
+		return
+	}
+
+	v.paused = true
+	v.following = false
+	v.info.draw()
 }
 
 func (v *viewer) navigateStart() {
@@ -428,6 +649,20 @@ func (v *viewer) navigateStart() {
 	v.draw()
 }
 
+// jumpToTime shows lines logged since t, the end of logs is shown when all of them are older
+func (v *viewer) jumpToTime(t time.Time) {
+	offset, ok := v.fetcher.searchTime(t)
+	if !ok {
+		v.navigateEnd()
+		return
+	}
+
+	v.direction = DirectionUP
+	v.following = false
+	v.buffer.reset(Pos{POS_UNKNOWN, offset})
+	v.draw()
+}
+
 func (v *viewer) navigateHorizontally(direction int) {
 	v.wrap = false
 	v.hOffset += direction
@@ -445,8 +680,21 @@ func (v *viewer) navigateLeft() {
 	v.navigateHorizontally(-v.width / 2)
 }
 
+// overlay returns focused view which takes the whole screen, e.g. summary, or nil
+func (v *viewer) overlay() View {
+	if v.focus == nil || v.focus == Focusing(v) || v.focus == Focusing(&v.info) {
+		return nil
+	}
+
+	return v.focus
+}
+
 func (v *viewer) resetFocus() {
+	closed := v.overlay() != nil
 	v.focus = v
+	if closed {
+		v.draw()
+	}
 	termbox.HideCursor()
 
 	logging.LogOnErr(termbox.Flush())
@@ -458,156 +706,96 @@ func (v *viewer) onUserAction() {
 
 func (v *viewer) processKey(ev termbox.Event) (a action) {
 	v.onUserAction()
-	if ev.Ch != 0 {
-		switch ev.Ch {
-		case 'W':
-			logging.Debug("switching wrapping")
-			v.wrap = !v.wrap
-			if v.wrap {
-				v.hOffset = 0
-			}
-			v.draw()
-		case 'q':
-			logging.Debug("got key quit")
-			return ACTION_QUIT
-		case 'n':
-			v.nextSearch(false)
-		case 'N':
-			v.nextSearch(true)
-		case 'h':
-			v.searchHighlighted()
-		case 'H':
-			v.searchBackHighlighted()
-		case 'U':
-			if ok := v.fetcher.removeLastFilter(); ok {
-				v.buffer.refresh()
-				v.draw()
-			}
-		case 'g':
-			v.navigateStart()
-		case 'G':
-			v.navigateEnd()
-		case 'f':
-			v.navigatePageDown()
-		case 'b':
-			v.navigatePageUp()
-		case '/':
-			v.focus = &v.info
-			v.info.reset(ibModeSearch)
-		case filters.FilterIntersectChar:
-			v.focus = &v.info
-			v.info.reset(ibModeFilter)
-		case filters.FilterUnionChar:
-			v.focus = &v.info
-			v.info.reset(ibModeAppend)
-		case filters.FilterExcludeChar:
-			v.focus = &v.info
-			v.info.reset(ibModeExclude)
-		case filters.FilterHighlightChar:
-			v.focus = &v.info
-			v.info.reset(ibModeHighlight)
-		case '`':
-			v.fetcher.toggleHighlight(v.buffer.currentLine().Pos.Line)
-			v.buffer.toggleCurrentHighlight()
-			v.draw()
-		case '?':
-			v.focus = &v.info
-			v.info.reset(ibModeBackSearch)
-		case 'M':
-			reportSystemUsage()
-		case '=':
-			v.dropFilters()
-		case 'C':
-			v.switchFilters()
-		case 'K':
-			v.focus = &v.info
-			v.info.reset(ibModeKeepCharacters)
-		case 'j':
-			v.navigate(+1)
-		case 'k':
-			v.navigate(-1)
-		case '>':
-			v.navigateHorizontally(+1)
-		case '<':
-			v.navigateHorizontally(-1)
-
-		}
-	} else {
-		switch ev.Key {
-		case termbox.KeyEsc:
-			logging.Debug("got key quit")
-			return ACTION_QUIT
-		case termbox.KeyArrowDown:
-			v.navigate(+1)
-		case termbox.KeyArrowUp:
-			v.navigate(-1)
-		case termbox.KeyArrowRight:
-			v.keyArrowRight()
-		case termbox.KeyArrowLeft:
-			v.keyArrowLeft()
-		case termbox.KeyCtrlB, termbox.KeyPgup:
-			v.navigatePageUp()
-		case termbox.KeyCtrlU:
-			v.navigateHalfPageUp()
-		case termbox.KeyCtrlF, termbox.KeyPgdn, termbox.KeySpace:
-			v.navigatePageDown()
-		case termbox.KeyCtrlD:
-			v.navigateHalfPageDown()
-		case termbox.KeyHome:
-			v.navigateStart()
-		case termbox.KeyEnd:
-			v.navigateEnd()
-		case termbox.KeyCtrlH:
-			v.dropHighlights()
-		}
+	if v.markKey != 0 {
+		v.processMarkKey(ev)
+		return
+	}
+	if b := lookup(keymap, ev); b != nil {
+		return b.do(v, ev)
 	}
 	return
 }
 
+func (v *viewer) toggleWrap() {
+	logging.Debug("switching wrapping")
+	v.wrap = !v.wrap
+	if v.wrap {
+		v.hOffset = 0
+	}
+	v.draw()
+}
+
+func (v *viewer) removeLastFilter() {
+	if ok := v.fetcher.removeLastFilter(); ok {
+		v.buffer.refresh()
+		v.draw()
+	}
+}
+
+func (v *viewer) toggleCurrentHighlight() {
+	i := v.currentIndex()
+	line, err := v.buffer.getLine(i)
+	if err != nil {
+		return
+	}
+	v.fetcher.toggleHighlight(line.Pos.Line)
+	v.buffer.toggleHighlight(i)
+	v.draw()
+}
+
+// place moves the pane to position x, y of the screen and resizes it
+func (v *viewer) place(x, y, width, height int) {
+	v.x, v.y = x, y
+	v.resize(width, height)
+}
+
 func (v *viewer) resize(width, height int) {
 	v.sizeLock.Lock()
-	v.width, v.height = width, height
+	v.width, v.height, v.rows = width, height, height
 	v.height-- // Saving one Line for infobar
+	infobarY := v.height
+	if v.sparkline {
+		v.height--
+	}
 	v.sizeLock.Unlock()
-	v.info.resize(v.width, v.height)
+	v.info.resize(v.x, v.y+infobarY, v.width)
 	v.buffer.window = v.height
 	v.draw()
 }
 
 type infobarRequest struct {
+	v    *viewer
 	str  []rune
 	mode infoBarMode
 }
 
-var requestSearch = make(chan infobarRequest)
-var requestRefresh = make(chan struct{})
-var requestRefill = make(chan struct{})
-var requestStatusUpdate = make(chan LineNo)
-var requestKeepCharsChange = make(chan int)
-var lastLineControl = make(chan struct{})
-
-func (v *viewer) termGui(terminalName string, callback func()) {
-	if err := termbox.Init(); err != nil {
-		panic(err)
-	}
-	defer termbox.Close()
-
-	wg := sync.WaitGroup{}
-	ctx, cancel := context.WithCancel(v.ctx)
-	defer func() {
-		cancel()
-		wg.Wait()
-	}()
-
-	termbox.SetInputMode(termbox.InputEsc)
-	termbox.SetOutputMode(termbox.Output256)
+// paneRequest delivers value from background goroutine to event loop, for viewer of the pane
+type paneRequest[T any] struct {
+	v     *viewer
+	value T
+}
 
+var requestSearch = make(chan infobarRequest)
+var requestRefresh = make(chan *viewer)
+var requestRefill = make(chan *viewer)
+var requestStatusUpdate = make(chan paneRequest[LineNo])
+var requestHistogram = make(chan paneRequest[*histogram])
+var requestSummary = make(chan summaryProgress)
+var requestMeterUpdate = make(chan paneRequest[*meterReading])
+var requestAlert = make(chan alert)
+var requestNewLines = make(chan paneRequest[int])
+var requestKeepCharsChange = make(chan paneRequest[int])
+
+// setup prepares viewer, it is placed on the screen afterwards
+func (v *viewer) setup(terminalName string) {
 	v.info = infoBar{
 		y:              0,
 		width:          0,
+		owner:          v,
 		currentLine:    &v.buffer.originalPos,
 		totalLines:     0,
 		filtersEnabled: &v.fetcher.filtersEnabled,
+		following:      &v.following,
 		keepChars:      &v.keepChars,
 		flock:          &v.fetcher.lock,
 		searchType:     filters.CaseSensitive,
@@ -617,72 +805,99 @@ func (v *viewer) termGui(terminalName string, callback func()) {
 	v.buffer = viewBuffer{
 		fetcher: v.fetcher,
 	}
+}
 
+// run shows the end of logs and starts goroutines updating the viewer until ctx is done
+func (v *viewer) run(ctx context.Context, wg *sync.WaitGroup, callback func()) {
 	v.initScreen()
-
-	v.resize(termbox.Size())
 	v.navigateEnd()
 
 	callback()
 
-	wg.Add(3)
+	wg.Add(4)
 	go func() { v.refreshIfEmpty(ctx); wg.Done() }()
+	go func() { v.measure(ctx); wg.Done() }()
 	go func() { v.updateLastLine(ctx); wg.Done() }()
 	go func() { v.follow(ctx); wg.Done() }()
 
-loop:
-	for {
-		switch ev := termbox.PollEvent(); ev.Type {
-		case termbox.EventKey:
-			action := v.focus.processKey(ev)
-			switch action {
-			case ACTION_QUIT:
-				break loop
-			case ACTION_RESET_FOCUS:
-				v.resetFocus()
+	if rules, err := loadRules(); err != nil {
+		v.info.setMessage(ibMessage{str: "Err: watch rules: " + err.Error(), color: termbox.ColorRed})
+	} else if len(rules) > 0 {
+		wg.Add(1)
+		go func() { v.watch(ctx, rules); wg.Done() }()
+	}
+}
+
+// processRequest handles request delivered by interrupt, the request is dropped when its pane is closed
+func processRequest(isOpen func(v *viewer) bool) {
+	select {
+	case search := <-requestSearch:
+		if isOpen(search.v) {
+			search.v.processInfobarRequest(search)
+		}
+	case v := <-requestRefresh:
+		if isOpen(v) {
+			v.buffer.refresh()
+			v.draw()
+		}
+	case v := <-requestRefill: // It is not most efficient solution, it might cause huge amount of redraws
+		if isOpen(v) {
+			v.refill()
+		}
+	case r := <-requestMeterUpdate:
+		if v := r.v; isOpen(v) {
+			v.info.meter = r.value
+			if v.focus == v && v.info.mode == ibModeStatus {
+				v.info.draw()
 			}
-		case termbox.EventResize:
-			logging.Debug("Resize event", ev.Width, ev.Height)
-			v.resize(ev.Width, ev.Height)
-		case termbox.EventError:
-			panic(ev.Err)
-		case termbox.EventInterrupt:
-			select {
-			case search := <-requestSearch:
-				v.processInfobarRequest(search)
-			case <-requestRefresh:
-				v.buffer.refresh()
-				v.draw()
-			case <-requestRefill: // It is not most efficient solution, it might cause huge amount of redraws
-				v.refill()
-			case line := <-requestStatusUpdate:
-				v.info.totalLines = line + 1
-				if v.focus == v {
-					v.info.draw()
-				}
-			case charChange := <-requestKeepCharsChange:
-				if v.keepChars+charChange >= 0 {
-					v.keepChars += charChange
-				}
-				v.draw()
+		}
+	case r := <-requestNewLines:
+		if v := r.v; isOpen(v) {
+			v.info.newLines = r.value
+			if v.focus == v && v.info.mode == ibModeStatus {
+				v.info.draw()
+			}
+		}
+	case a := <-requestAlert:
+		if isOpen(a.v) {
+			a.v.alert(a)
+		}
+	case r := <-requestHistogram:
+		if isOpen(r.v) {
+			r.v.setHistogram(r.value)
+		}
+	case p := <-requestSummary:
+		if isOpen(p.s.v) && p.s.v.focus == Focusing(p.s) {
+			p.s.update(p)
+		}
+	case r := <-requestStatusUpdate:
+		if v := r.v; isOpen(v) {
+			v.info.totalLines = r.value + 1
+			if v.focus == v {
+				v.info.draw()
+			}
+		}
+	case r := <-requestKeepCharsChange:
+		if v := r.v; isOpen(v) {
+			if v.keepChars+r.value >= 0 {
+				v.keepChars += r.value
 			}
+			v.draw()
 		}
 	}
 }
 
 func (v *viewer) initScreen() {
-	logging.LogOnErr(termbox.Clear(termbox.ColorDefault, termbox.ColorDefault))
+	v.clear()
 	v.buffer.reset(Pos{0, 0})
 
 	v.resetLastLine()
 
-	tx, ty := termbox.Size()
-
 	str := []rune("Waiting log data...")
-	tx = tx/2 - len(str)/2
-	ty /= 2
+	tx := v.width/2 - len(str)/2
+	ty := v.rows / 2
 	for i := 0; i < len(str); i++ {
-		termbox.SetCell(tx+i, ty, str[i], termbox.ColorYellow, termbox.ColorDefault)
+		v.setCell(tx+i, ty, str[i], termbox.ColorYellow, termbox.ColorDefault)
 	}
 
 	logging.LogOnErr(termbox.Flush())
@@ -764,7 +979,7 @@ loop:
 func (v *viewer) resetLastLine() {
 	go func() {
 		select {
-		case lastLineControl <- struct{}{}:
+		case v.lastLineControl <- struct{}{}:
 		}
 	}()
 }
@@ -776,7 +991,7 @@ func (v *viewer) updateLastLine(ctx context.Context) {
 loop:
 	for {
 		select {
-		case <-lastLineControl:
+		case <-v.lastLineControl:
 			lastLine = Pos{0, 0}
 			delay = 5 * time.Millisecond
 		case <-ctx.Done():
@@ -788,7 +1003,7 @@ loop:
 			if lastLine != prevLine {
 				go termbox.Interrupt()
 				select {
-				case requestStatusUpdate <- lastLine.Line:
+				case requestStatusUpdate <- paneRequest[LineNo]{v, lastLine.Line}:
 					v.fetcher.updateMap(dataLine)
 				case <-ctx.Done():
 					return
@@ -807,11 +1022,32 @@ loop:
 func (v *viewer) follow(ctx context.Context) {
 	delay := 100 * time.Millisecond
 	lastOffset := v.fetcher.lastWROffset()
+	_, _, written := v.fetcher.reader.Written(0)
+	newLines := 0
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-time.After(delay):
+			// counting lines arrived while following is paused
+			data, _, total := v.fetcher.reader.Written(written)
+			written = total
+			n := newLines
+			if v.following {
+				n = 0
+			} else {
+				n += bytes.Count(data, []byte{'\n'})
+			}
+			if n != newLines {
+				newLines = n
+				go termbox.Interrupt()
+				select {
+				case requestNewLines <- paneRequest[int]{v, n}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
 			if v.following {
 				prevOffset := lastOffset
 				lastOffset = v.fetcher.lastWROffset()
@@ -819,7 +1055,7 @@ func (v *viewer) follow(ctx context.Context) {
 					go func() {
 						go termbox.Interrupt()
 						select {
-						case requestRefill <- struct{}{}:
+						case requestRefill <- v:
 						case <-ctx.Done():
 							return
 						}
@@ -879,6 +1115,156 @@ func (v *viewer) navigateHalfPageDown() {
 	v.navigate(v.height / 2)
 }
 
+// rebaseMarks moves lines marked by offset when history is inserted in front of them,
+// marks are dropped when file is cleared, as it is done by switching container
+func (v *viewer) rebaseMarks() {
+	version := v.fetcher.reader.Version()
+	if version == v.marksVersion {
+		return
+	}
+	n, ok := v.fetcher.reader.Moved(v.marksVersion)
+	v.marksVersion = version
+	v.expanded = rebaseOffsets(v.expanded, Offset(n), ok)
+	v.folded = rebaseOffsets(v.folded, Offset(n), ok)
+	switch {
+	case v.selected < 0:
+	case ok:
+		v.selected += Offset(n)
+	default:
+		v.selected = -1
+	}
+}
+
+// rebaseOffsets returns marks moved by n, nil when their offsets are not valid anymore
+func rebaseOffsets(marks map[Offset]bool, n Offset, ok bool) map[Offset]bool {
+	if !ok || len(marks) == 0 {
+		return nil
+	}
+	if n == 0 {
+		return marks
+	}
+	moved := make(map[Offset]bool, len(marks))
+	for offset, mark := range marks {
+		moved[offset+n] = mark
+	}
+
+	return moved
+}
+
+// toggleExpanded switches current line between compact and indented multi-line JSON
+func (v *viewer) toggleExpanded() {
+	v.rebaseMarks()
+	if v.expanded == nil {
+		v.expanded = make(map[Offset]bool)
+	}
+	offset := v.currentLine().Offset
+	if v.expanded[offset] {
+		delete(v.expanded, offset)
+	} else {
+		v.expanded[offset] = true
+	}
+	v.draw()
+}
+
+// toggleFolded folds multi-line entry on the current line into its first line
+func (v *viewer) toggleFolded() {
+	v.rebaseMarks()
+	if v.folded == nil {
+		v.folded = make(map[Offset]bool)
+	}
+	offset := v.currentLine().Offset
+	if v.folded[offset] {
+		delete(v.folded, offset)
+	} else {
+		v.folded[offset] = true
+	}
+	v.draw()
+}
+
+func foldEntry(str ansi.Astring) ansi.Astring {
+	i := runes.IndexRune(str.Runes, '\n')
+	if i == -1 {
+		return str
+	}
+
+	lines := 0
+	for _, r := range str.Runes[i:] {
+		if r == '\n' {
+			lines++
+		}
+	}
+
+	folded := ansi.Astring{
+		Runes: append([]rune(nil), str.Runes[:i]...),
+		Attrs: append([]ansi.RuneAttr(nil), str.Attrs[:i]...),
+		Links: str.Links,
+	}
+	folded.AppendString(fmt.Sprintf(" [+%d lines]", lines), ansi.RuneAttr{Fg: ansi.FgColor(ansi.ColorBlue)})
+
+	return folded
+}
+
+// openLink opens or copies to clipboard target of the first OSC 8 hyperlink of current line
+func (v *viewer) openLink(copy bool) {
+	links := v.currentLine().Str.Links
+	if len(links) == 0 {
+		v.info.setMessage(ibMessage{str: "No links in current line", color: termbox.ColorRed})
+		return
+	}
+
+	var err error
+	done := "Opened "
+	if copy {
+		done = "Copied "
+		err = utils.CopyToClipboard(links[0])
+	} else {
+		err = utils.OpenURL(links[0])
+	}
+	if err != nil {
+		v.info.setMessage(ibMessage{str: "Err: " + err.Error(), color: termbox.ColorRed})
+		return
+	}
+	v.info.setMessage(ibMessage{str: done + links[0], color: termbox.ColorGreen})
+}
+
+// switchGrouping switches between reading multi-line entries as one line and line by line
+func (v *viewer) switchGrouping() {
+	v.fetcher.switchGrouping()
+	v.folded = nil
+	v.resetLastLine()
+	v.buffer.reset(Pos{POS_UNKNOWN, v.buffer.currentLine().Offset})
+	v.draw()
+
+	state := "off"
+	if v.fetcher.grouping {
+		state = "on"
+	}
+	v.info.setMessage(ibMessage{str: "Multi-line entries grouping " + state, color: termbox.ColorGreen})
+}
+
+func (v *viewer) switchDedup() {
+	mode := v.fetcher.switchDedup()
+	v.buffer.refresh()
+	v.draw()
+	v.info.setMessage(ibMessage{str: "Collapse duplicates: " + dedupModeNames[mode], color: termbox.ColorGreen})
+}
+
+func (v *viewer) showSummary() {
+	v.focus = newSummaryView(v)
+	v.draw()
+}
+
+// showDetail opens current line in detail overlay
+func (v *viewer) showDetail() {
+	l := v.currentLine()
+	if len(l.Text) == 0 {
+		return
+	}
+
+	v.focus = newDetailView(v, l)
+	v.draw()
+}
+
 func (v *viewer) dropFilters() {
 	v.fetcher.lock.Lock()
 	newFilters := make([]*filters.Filter, 0)
@@ -889,6 +1275,7 @@ func (v *viewer) dropFilters() {
 		}
 	}
 	v.fetcher.filters = newFilters
+	v.fetcher.filtersVersion++
 	v.fetcher.lock.Unlock()
 	v.buffer.refresh()
 	v.draw()
@@ -903,6 +1290,7 @@ func (v *viewer) dropHighlights() {
 		}
 	}
 	v.fetcher.filters = newFilters
+	v.fetcher.filtersVersion++
 	v.fetcher.highlightedLines = v.fetcher.highlightedLines[:0]
 	v.fetcher.lock.Unlock()
 	v.buffer.refresh()
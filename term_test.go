@@ -0,0 +1,113 @@
+package dlog
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dimcz/dlog/ansi"
+	"github.com/dimcz/dlog/filters"
+	"github.com/dimcz/dlog/memfile"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestRebaseMarks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	file := memfile.New([]byte("a\nb\n"))
+	v := &viewer{fetcher: NewFetcher(ctx, file), selected: 2}
+	v.expanded = map[Offset]bool{0: true}
+	v.folded = map[Offset]bool{2: true}
+
+	tests := []struct {
+		step     func()
+		expanded map[Offset]bool
+		folded   map[Offset]bool
+		selected Offset
+	}{
+		{func() {}, map[Offset]bool{0: true}, map[Offset]bool{2: true}, 2},
+		{func() { _, _ = file.Insert([]byte("old\n")) }, map[Offset]bool{4: true}, map[Offset]bool{6: true}, 6},
+		{func() { _, _ = file.Write([]byte("c\n")) }, map[Offset]bool{4: true}, map[Offset]bool{6: true}, 6},
+		{func() { _, _ = file.Insert([]byte("x\n")); _, _ = file.Insert([]byte("y\n")) }, map[Offset]bool{8: true}, map[Offset]bool{10: true}, 10},
+		{func() { file.Clear(); _, _ = file.Insert([]byte("other\n")) }, nil, nil, -1},
+	}
+
+	for i, tt := range tests {
+		tt.step()
+		v.rebaseMarks()
+		if !reflect.DeepEqual(v.expanded, tt.expanded) || !reflect.DeepEqual(v.folded, tt.folded) || v.selected != tt.selected {
+			t.Errorf("test %d, rebaseMarks:\ngot  %v %v %d\nwant %v %v %d", i, v.expanded, v.folded, v.selected, tt.expanded, tt.folded, tt.selected)
+		}
+	}
+}
+
+// TestRenderLineHighlight checks that matches in text of line are highlighted in its shown form
+func TestRenderLineHighlight(t *testing.T) {
+	tests := []struct {
+		text     string
+		search   string
+		expanded bool
+		repeated int
+		want     string // highlighted runes are in brackets
+	}{
+		{"2022-07-14T10:00:00Z started", "start", false, 0, "[start]ed"},
+		{"2022-07-14T10:00:00Z started", "00Z st", false, 0, "[st]arted"},
+		{"2022-07-14T10:00:00Z started", "ed", false, 3, "start[ed] ×3"},
+		{`{"msg":"ok","level":"info","user":"bob"}`, "info", false, 0, "[INFO] ok user=bob"},
+		{`{"msg":"ok","level":"info","user":"bob"}`, `"user":"bob"`, false, 0, "INFO ok [user]=[bob]"},
+		{`{"msg":"ok","level":"info","user":"bob"}`, "user=bob", false, 0, "INFO ok user=bob"},
+		{`{"msg":"two words","n": 1}`, `"n": 1`, false, 0, `two words [n]=[1]`},
+		{`{"msg":"ok", "n":1}`, `ok", "n`, true, 0, "{\n  \"msg\": \"[ok\",]\n  [\"n]\": 1\n}"},
+	}
+
+	for i, tt := range tests {
+		v := &viewer{expanded: map[Offset]bool{}}
+		if tt.expanded {
+			v.expanded[0] = true
+		}
+		line := Line{Str: ansi.NewAstring([]byte(tt.text)), Text: []byte(tt.text), Repeated: tt.repeated}
+		str, src := v.renderLine(line)
+		indexAll, _ := filters.GetIndexAllFunc(filters.CaseSensitive, []rune(tt.search))
+		hl := highlightedRunes(indexAll, line.Str.Runes, str.Runes, src)
+
+		var got []rune
+		for j, r := range str.Runes {
+			if hl[j] && (j == 0 || !hl[j-1]) {
+				got = append(got, '[')
+			}
+			if !hl[j] && j != 0 && hl[j-1] {
+				got = append(got, ']')
+			}
+			got = append(got, r)
+		}
+		if len(hl) != 0 && hl[len(hl)-1] {
+			got = append(got, ']')
+		}
+		if string(got) != tt.want {
+			t.Errorf("test %d, highlight %q in %q:\ngot  %q\nwant %q", i, tt.search, tt.text, string(got), tt.want)
+		}
+	}
+}
+
+func TestToTermboxAttr(t *testing.T) {
+	tests := []struct {
+		params string
+		fg, bg termbox.Attribute
+	}{
+		{"31", 1 + 1, 0},
+		{"1;31", 9 + 1 | termbox.AttrBold, 0},
+		{"1;41", termbox.AttrBold, 9 + 1},
+		{"1;38;5;1", 1 + 1 | termbox.AttrBold, 0},
+		{"1;48;5;1", termbox.AttrBold, 1 + 1},
+		{"1;91", 9 + 1 | termbox.AttrBold, 0},
+		{"1;38;5;208", 208 + 1 | termbox.AttrBold, 0},
+	}
+
+	for i, tt := range tests {
+		a := ansi.NewAstring([]byte("\x1b[" + tt.params + "mx"))
+		if fg, bg := ToTermboxAttr(a.Attrs[0]); fg != tt.fg || bg != tt.bg {
+			t.Errorf("test %d, ToTermboxAttr(%s):\ngot  %d, %d\nwant %d, %d", i, tt.params, fg, bg, tt.fg, tt.bg)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package timestamp detects RFC3339 timestamp docker prefixes lines with, when asked for timestamps
+package timestamp
+
+import (
+	"bytes"
+	"time"
+)
+
+// Parse parses timestamp in beginning of line, n is its length including following space,
+// 0 if line does not start with timestamp
+func Parse(line []rune) (t time.Time, n int) {
+	i := 0
+	for i < len(line) && line[i] != ' ' {
+		i++
+	}
+	if i == 0 || i == len(line) {
+		return time.Time{}, 0
+	}
+
+	return parse(string(line[:i]), i)
+}
+
+// ParseBytes is Parse of line encoded as UTF-8, n is length in bytes
+func ParseBytes(line []byte) (t time.Time, n int) {
+	i := bytes.IndexByte(line, ' ')
+	if i <= 0 {
+		return time.Time{}, 0
+	}
+
+	return parse(string(line[:i]), i)
+}
+
+// Strip returns line without leading timestamp
+func Strip(line []byte) []byte {
+	_, n := ParseBytes(line)
+
+	return line[n:]
+}
+
+func parse(s string, i int) (time.Time, int) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, 0
+	}
+
+	return t, i + 1
+}
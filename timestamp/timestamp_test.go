@@ -0,0 +1,41 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line  string
+		want  string
+		wantN int
+		strip string
+	}{
+		{"2022-07-14T10:15:42.123456789Z INFO started", "2022-07-14T10:15:42.123456789Z", 31, "INFO started"},
+		{"2022-07-14T10:15:42Z ", "2022-07-14T10:15:42Z", 21, ""},
+		{"2022-07-14T10:15:42Z", "", 0, "2022-07-14T10:15:42Z"},
+		{"INFO started", "", 0, "INFO started"},
+		{" indented", "", 0, " indented"},
+		{"2022-07-14 10:15:42 INFO started", "", 0, "2022-07-14 10:15:42 INFO started"},
+		{"2022-07-14T10:15:42Z café", "2022-07-14T10:15:42Z", 21, "café"},
+		{"", "", 0, ""},
+	}
+
+	for i, tt := range tests {
+		var want time.Time
+		if tt.want != "" {
+			want, _ = time.Parse(time.RFC3339Nano, tt.want)
+		}
+
+		if got, n := Parse([]rune(tt.line)); !got.Equal(want) || n != tt.wantN {
+			t.Errorf("test %d, Parse(%q):\ngot  %v, %d\nwant %v, %d", i, tt.line, got, n, want, tt.wantN)
+		}
+		if got, n := ParseBytes([]byte(tt.line)); !got.Equal(want) || n != tt.wantN {
+			t.Errorf("test %d, ParseBytes(%q):\ngot  %v, %d\nwant %v, %d", i, tt.line, got, n, want, tt.wantN)
+		}
+		if got := string(Strip([]byte(tt.line))); got != tt.strip {
+			t.Errorf("test %d, Strip(%q):\ngot  %q\nwant %q", i, tt.line, got, tt.strip)
+		}
+	}
+}
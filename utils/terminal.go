@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// WriteTerminal writes raw data, like bell or escape sequence, to the controlling terminal
+func WriteTerminal(data []byte) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tty.Write(data); err != nil {
+		_ = tty.Close()
+		return err
+	}
+
+	return tty.Close()
+}
+
+// CopyToClipboard asks terminal to put text into system clipboard with OSC 52 sequence,
+// so it works over ssh as well. Terminal has to allow clipboard access
+func CopyToClipboard(text string) error {
+	return WriteTerminal([]byte("\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"))
+}
+
+// OpenURL opens http or https url with default application of desktop, url comes from log content,
+// so other schemes, like file://, are refused and url is never passed through shell
+func OpenURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", target, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("open %s: only http and https links are opened", target)
+	}
+	target = u.String()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "--", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open %s: %w", target, err)
+	}
+
+	go func() { _ = cmd.Wait() }()
+
+	return nil
+}
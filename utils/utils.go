@@ -125,3 +125,13 @@ func ExitOnErr(err error) {
 		os.Exit(1)
 	}
 }
+
+// IsTerminal reports whether f is connected to a terminal, false for pipes and redirected files
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -56,7 +56,7 @@ func (b *viewBuffer) fill() fillResult {
 			curPos := len(b.buffer) - 1
 			if b.buffer[curPos].Offset == data.Offset {
 				// Same line as current
-				if len(b.buffer[curPos].Str.Runes) != len(data.Str.Runes) {
+				if len(b.buffer[curPos].Str.Runes) != len(data.Str.Runes) || b.buffer[curPos].Repeated != data.Repeated {
 					result.lastLineChanged = true
 					b.buffer[curPos] = data // Line changed, replacing
 				}
@@ -173,7 +173,7 @@ func (b *viewBuffer) searchForward(searchFunc filters.SearchFunc) int {
 			// TODO: Maintain search index?( to navigate inside string)
 			continue
 		}
-		if searchFunc(line.Str.Runes) != nil {
+		if searchFunc(line.Text) != nil {
 			return i
 		}
 	}
@@ -183,7 +183,7 @@ func (b *viewBuffer) searchForward(searchFunc filters.SearchFunc) int {
 func (b *viewBuffer) searchBack(searchFunc filters.SearchFunc) int {
 	prevLines := b.buffer[:b.pos]
 	for i := 1; i <= len(prevLines); i++ {
-		if searchFunc(prevLines[len(prevLines)-i].Str.Runes) != nil {
+		if searchFunc(prevLines[len(prevLines)-i].Text) != nil {
 			return i
 		}
 	}
@@ -257,6 +257,8 @@ func (b *viewBuffer) shiftToEnd() {
 	}
 	b.pos = len(b.buffer) - b.window
 }
-func (b *viewBuffer) toggleCurrentHighlight() {
-	b.buffer[b.pos].Highlighted = !b.buffer[b.pos].Highlighted
+
+// toggleHighlight toggles mark of line i, relative to the top line
+func (b *viewBuffer) toggleHighlight(i int) {
+	b.buffer[b.pos+i].Highlighted = !b.buffer[b.pos+i].Highlighted
 }
@@ -0,0 +1,165 @@
+// Package watch implements alert rules evaluated on lines arriving while following
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Action uint
+
+const (
+	ActionFlash Action = 1 << iota // show alert in status bar
+	ActionBell                     // ring terminal bell
+	ActionPause                    // stop following at the matching line
+	ActionRun                      // run command with the line on stdin
+)
+
+var actionNames = map[string]Action{
+	"flash": ActionFlash,
+	"bell":  ActionBell,
+	"pause": ActionPause,
+	"run":   ActionRun,
+}
+
+// Rule fires when Pattern matches Threshold lines within Window, or every matching line without threshold
+type Rule struct {
+	Name      string
+	Pattern   *regexp.Regexp
+	Threshold int
+	Window    time.Duration
+	Actions   Action
+	Command   string
+
+	hits []time.Time
+}
+
+type ruleConfig struct {
+	Name      string   `json:"name"`
+	Pattern   string   `json:"pattern"`
+	Threshold int      `json:"threshold"`
+	Window    string   `json:"window"`
+	Actions   []string `json:"actions"`
+	Command   string   `json:"command"`
+}
+
+// Load reads rules from JSON file like
+//
+//	[
+//	  {"pattern": "OOMKilled", "actions": ["pause", "bell"]},
+//	  {"name": "timeouts", "pattern": "(?i)timeout", "threshold": 5, "window": "1m", "actions": ["flash"]},
+//	  {"pattern": "panic:", "actions": ["run"], "command": "notify-send dlog \"$(head -c 200)\""}
+//	]
+//
+// Missing file means no rules
+func Load(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []ruleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	rules := make([]*Rule, 0, len(configs))
+	for i, c := range configs {
+		r, err := c.rule()
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (c ruleConfig) rule() (*Rule, error) {
+	re, err := regexp.Compile(c.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rule{
+		Name:      c.Name,
+		Pattern:   re,
+		Threshold: c.Threshold,
+		Command:   c.Command,
+	}
+	if r.Name == "" {
+		r.Name = c.Pattern
+	}
+
+	if c.Window != "" {
+		if r.Window, err = time.ParseDuration(c.Window); err != nil {
+			return nil, err
+		}
+	}
+	if r.Threshold > 1 && r.Window <= 0 {
+		return nil, fmt.Errorf("threshold requires window")
+	}
+
+	if len(c.Actions) == 0 {
+		c.Actions = []string{"flash"}
+	}
+	for _, name := range c.Actions {
+		a, ok := actionNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		r.Actions |= a
+	}
+	if r.Actions&ActionRun != 0 && r.Command == "" {
+		return nil, fmt.Errorf("run action requires command")
+	}
+
+	return r, nil
+}
+
+// Match reports whether the line arrived at now fires the rule
+func (r *Rule) Match(line []byte, now time.Time) bool {
+	if !r.Pattern.Match(line) {
+		return false
+	}
+	if r.Threshold <= 1 {
+		return true
+	}
+
+	r.hits = append(r.hits, now)
+	expired := 0
+	for expired < len(r.hits) && now.Sub(r.hits[expired]) > r.Window {
+		expired++
+	}
+	r.hits = r.hits[expired:]
+
+	if len(r.hits) < r.Threshold {
+		return false
+	}
+	r.hits = r.hits[:0]
+
+	return true
+}
+
+// Run executes rule command by shell with the line on stdin
+func (r *Rule) Run(ctx context.Context, line []byte) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.Command)
+	cmd.Stdin = io.MultiReader(bytes.NewReader(line), strings.NewReader("\n"))
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", r.Command, err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}